@@ -5,9 +5,17 @@ import (
 	"log"
 	"log/slog"
 	"net/http"
+	// The image is built from scratch, dashboard timezones come from the embedded database
+	_ "time/tzdata"
 
 	"github.com/bernardinorafael/globo-challenge/internal/config"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/challenge"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/client"
+	apimiddleware "github.com/bernardinorafael/globo-challenge/internal/infra/http/middleware"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/ratelimit"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/signer"
 	"github.com/bernardinorafael/globo-challenge/internal/metric"
+	"github.com/bernardinorafael/globo-challenge/internal/modules/admin"
 	"github.com/bernardinorafael/globo-challenge/internal/modules/elimination"
 	"github.com/bernardinorafael/globo-challenge/internal/modules/participant"
 	"github.com/bernardinorafael/globo-challenge/internal/modules/user"
@@ -24,7 +32,19 @@ func main() {
 	metrics := metric.NewMetric()
 	ctx := context.Background()
 
+	// Environment variables
+	env, err := config.NewEnv()
+	if err != nil {
+		log.Fatalf("error loading environment variables: %v", err)
+	}
+
+	realIP, err := apimiddleware.NewRealIP(env.TrustedProxies)
+	if err != nil {
+		log.Fatalf("error loading trusted proxies: %v", err)
+	}
+
 	r := chi.NewRouter()
+	r.Use(realIP)
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(cors.Handler(cors.Options{
@@ -37,12 +57,6 @@ func main() {
 
 	r.Handle("/metrics", promhttp.HandlerFor(metrics.GetRegistry(), promhttp.HandlerOpts{}))
 
-	// Environment variables
-	env, err := config.NewEnv()
-	if err != nil {
-		log.Fatalf("error loading environment variables: %v", err)
-	}
-
 	// RabbitMQ connection
 	rmq, err := queue.New(env.RabbitMQURI)
 	if err != nil {
@@ -59,9 +73,27 @@ func main() {
 	defer db.Close()
 	slog.Info("database connected")
 
+	// API clients
+	clients, err := client.NewRegistry(env.APIClients)
+	if err != nil {
+		log.Fatalf("error loading api clients: %v", err)
+	}
+
+	// Rate limiter
+	limiter, err := ratelimit.New(ctx, env.RateLimitBackend, db)
+	if err != nil {
+		log.Fatalf("error creating rate limiter: %v", err)
+	}
+
+	// Vote receipt signer
+	receiptSigner, err := signer.New(env.ReceiptSigningKey)
+	if err != nil {
+		log.Fatalf("error creating receipt signer: %v", err)
+	}
+
 	// User module
 	userRepo := user.NewRepository(db)
-	userService := user.NewService(ctx, userRepo, env.SecretKey)
+	userService := user.NewService(ctx, userRepo, env.SecretKey, clients)
 	user.NewController(userService, env.SecretKey).RegisterRoutes(r)
 
 	// Participant module
@@ -71,11 +103,66 @@ func main() {
 
 	// Elimination module
 	eliminationRepo := elimination.NewRepository(db)
-	eliminationService := elimination.NewService(ctx, eliminationRepo, participantService, rmq, metrics)
-	elimination.NewController(eliminationService, env.SecretKey).RegisterRoutes(r)
+	resultBroker := elimination.NewResultBroker(env.DSN, eliminationRepo, env.ResultStreamInterval)
+	if err := resultBroker.Start(ctx); err != nil {
+		log.Fatalf("error starting result broker: %v", err)
+	}
+	eliminationService := elimination.NewService(
+		ctx,
+		eliminationRepo,
+		participantService,
+		rmq,
+		metrics,
+		resultBroker,
+		limiter,
+		elimination.VoteLimits{
+			User:        ratelimit.Limit{Rate: env.RateLimitUserRate, Burst: env.RateLimitUserBurst},
+			IP:          ratelimit.Limit{Rate: env.RateLimitIPRate, Burst: env.RateLimitIPBurst},
+			Elimination: ratelimit.Limit{Rate: env.RateLimitEliminationRate, Burst: env.RateLimitEliminationBurst},
+		},
+		receiptSigner,
+	)
+	challenges, err := challenge.NewIssuer(db, env.ChallengeSecret, challenge.Options{
+		BaseDifficulty: env.ChallengeBaseDifficulty,
+		MaxDifficulty:  env.ChallengeMaxDifficulty,
+		FreeRate:       env.ChallengeFreeRate,
+		Window:         env.ChallengeWindow,
+		TTL:            env.ChallengeTTL,
+	})
+	if err != nil {
+		log.Fatalf("error creating challenge issuer: %v", err)
+	}
+	challenges.Start(ctx)
+	elimination.NewController(eliminationService, challenges, env.SecretKey).RegisterRoutes(r)
+	elimination.NewScheduler(
+		db,
+		eliminationRepo,
+		eliminationService,
+		env.SchedulerInterval,
+		// Other consumers flush the votes they hold within two flush intervals, and
+		// a vote that failed before the barrier is back from the retry queues
+		// once every retry delay has passed
+		env.VoteFlushInterval*2+queue.RetryWindow(),
+		env.CloseDrainTimeout,
+	).Start(ctx)
+
+	// Admin module
+	adminService := admin.NewService(ctx, rmq)
+	admin.NewController(adminService, env.SecretKey).RegisterRoutes(r)
 
 	// Consumers
-	votesConsumer := elimination.NewConsumer(rmq, metrics, eliminationRepo)
+	tally := elimination.NewTally(eliminationRepo, metrics, env.VoteTallyFlushInterval)
+	tally.Start(ctx)
+	votesConsumer := elimination.NewConsumer(
+		rmq,
+		metrics,
+		eliminationRepo,
+		tally,
+		receiptSigner,
+		env.VoteBatchSize,
+		env.VoteFlushInterval,
+		env.UnsignedVoteGrace,
+	)
 	if err := votesConsumer.Consume(ctx); err != nil {
 		log.Fatalf("error starting votes consumer: %v", err)
 	}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/bernardinorafael/globo-challenge/internal/config"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/signer"
+	"github.com/bernardinorafael/globo-challenge/internal/modules/elimination"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Walks the vote ledger of an elimination, or of every elimination when none
+// is given, and reports the first break of each chain
+// With -backfill the votes stored before the ledger existed are chained first
+// Exits with status 1 when any chain is broken
+func main() {
+	ctx := context.Background()
+
+	eliminationId := flag.String("elimination", "", "ID of the elimination to audit, every elimination when empty")
+	backfill := flag.Bool("backfill", false, "chain the votes stored before the ledger existed before auditing")
+	flag.Parse()
+
+	// Environment variables
+	env, err := config.NewEnv()
+	if err != nil {
+		log.Fatalf("error loading environment variables: %v", err)
+	}
+
+	// Database connection
+	db, err := sqlx.Open("postgres", env.DSN)
+	if err != nil {
+		log.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	repo := elimination.NewRepository(db)
+
+	// Certificates are checked with the key vote receipts are signed with
+	verifier, err := signer.New(env.ReceiptSigningKey)
+	if err != nil {
+		log.Fatalf("error creating receipt signer: %v", err)
+	}
+
+	var ids = []string{*eliminationId}
+	if *eliminationId == "" {
+		eliminations, err := repo.GetAll(ctx)
+		if err != nil {
+			log.Fatalf("error getting eliminations: %v", err)
+		}
+		ids = ids[:0]
+		for _, e := range eliminations {
+			ids = append(ids, e.ID)
+		}
+	}
+
+	var broken bool
+	for _, id := range ids {
+		if *backfill {
+			chained, err := repo.BackfillLedger(ctx, id)
+			if err != nil {
+				log.Fatalf("error backfilling elimination %s: %v", id, err)
+			}
+			if chained > 0 {
+				fmt.Printf("%s backfilled %d votes\n", id, chained)
+			}
+		}
+
+		audit, err := elimination.AuditLedger(ctx, repo, verifier, id)
+		if err != nil {
+			log.Fatalf("error auditing elimination %s: %v", id, err)
+		}
+
+		if audit.Break == nil {
+			fmt.Printf("%s ok      %6d entries %8d votes head %s anchored %d\n", id, audit.Entries, audit.Votes, audit.Head, audit.Anchored)
+			continue
+		}
+
+		broken = true
+		if audit.Break.Seq == 0 {
+			fmt.Printf("%s broken %s\n", id, audit.Break.Reason)
+		} else {
+			fmt.Printf("%s broken at entry %d: %s\n", id, audit.Break.Seq, audit.Break.Reason)
+		}
+	}
+
+	if broken {
+		os.Exit(1)
+	}
+}
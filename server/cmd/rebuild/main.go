@@ -0,0 +1,62 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/bernardinorafael/globo-challenge/internal/config"
+	"github.com/bernardinorafael/globo-challenge/internal/modules/elimination"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// Recomputes the vote counters of an elimination, or of every elimination when
+// none is given, from the stored votes
+func main() {
+	ctx := context.Background()
+
+	eliminationId := flag.String("elimination", "", "ID of the elimination to rebuild, every elimination when empty")
+	flag.Parse()
+
+	// Environment variables
+	env, err := config.NewEnv()
+	if err != nil {
+		log.Fatalf("error loading environment variables: %v", err)
+	}
+
+	// Database connection
+	db, err := sqlx.Open("postgres", env.DSN)
+	if err != nil {
+		log.Fatalf("error opening database: %v", err)
+	}
+	defer db.Close()
+
+	repo := elimination.NewRepository(db)
+
+	var ids = []string{*eliminationId}
+	if *eliminationId == "" {
+		eliminations, err := repo.GetAll(ctx)
+		if err != nil {
+			log.Fatalf("error getting eliminations: %v", err)
+		}
+		ids = ids[:0]
+		for _, e := range eliminations {
+			ids = append(ids, e.ID)
+		}
+	}
+
+	for _, id := range ids {
+		rebuild, err := repo.RebuildCounters(ctx, id)
+		if err != nil {
+			log.Fatalf("error rebuilding elimination %s: %v", id, err)
+		}
+
+		if rebuild.Before == rebuild.After {
+			fmt.Printf("%s ok      %8d votes\n", id, rebuild.After)
+			continue
+		}
+		fmt.Printf("%s drifted %8d votes, counters had %d\n", id, rebuild.After, rebuild.Before)
+	}
+}
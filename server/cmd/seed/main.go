@@ -30,12 +30,12 @@ func main() {
 	defer db.Close()
 
 	var participants = []participant.Entity{
-		{ID: util.GenID("partic"), Name: "joão da silva", Picture: nil, EliminationID: nil, Created: time.Now(), Updated: time.Now()},
-		{ID: util.GenID("partic"), Name: "maria oliveira", Picture: nil, EliminationID: nil, Created: time.Now(), Updated: time.Now()},
-		{ID: util.GenID("partic"), Name: "pedro santos", Picture: nil, EliminationID: nil, Created: time.Now(), Updated: time.Now()},
-		{ID: util.GenID("partic"), Name: "ana souza", Picture: nil, EliminationID: nil, Created: time.Now(), Updated: time.Now()},
-		{ID: util.GenID("partic"), Name: "carlos pereira", Picture: nil, EliminationID: nil, Created: time.Now(), Updated: time.Now()},
-		{ID: util.GenID("partic"), Name: "laura costa", Picture: nil, EliminationID: nil, Created: time.Now(), Updated: time.Now()},
+		{ID: util.GenID("partic"), Name: "joão da silva", Picture: nil, Status: participant.StatusActive, Created: time.Now(), Updated: time.Now()},
+		{ID: util.GenID("partic"), Name: "maria oliveira", Picture: nil, Status: participant.StatusActive, Created: time.Now(), Updated: time.Now()},
+		{ID: util.GenID("partic"), Name: "pedro santos", Picture: nil, Status: participant.StatusActive, Created: time.Now(), Updated: time.Now()},
+		{ID: util.GenID("partic"), Name: "ana souza", Picture: nil, Status: participant.StatusActive, Created: time.Now(), Updated: time.Now()},
+		{ID: util.GenID("partic"), Name: "carlos pereira", Picture: nil, Status: participant.StatusActive, Created: time.Now(), Updated: time.Now()},
+		{ID: util.GenID("partic"), Name: "laura costa", Picture: nil, Status: participant.StatusActive, Created: time.Now(), Updated: time.Now()},
 	}
 
 	_, err = db.NamedExecContext(
@@ -45,14 +45,14 @@ func main() {
 				id,
 				name,
 				picture,
-				elimination_id,
+				status,
 				created,
 				updated
 			) VALUES (
 				:id,
 				:name,
 				:picture,
-				:elimination_id,
+				:status,
 				:created,
 				:updated
 			)
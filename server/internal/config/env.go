@@ -1,6 +1,10 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"time"
+
 	"github.com/spf13/viper"
 )
 
@@ -9,6 +13,53 @@ type Env struct {
 	DSN         string `mapstructure:"DB_POSTGRES_DSN"`
 	SecretKey   string `mapstructure:"ACCESS_TOKEN_SECRET"`
 	RabbitMQURI string `mapstructure:"RABBITMQ_URI"`
+	// TrustedProxies lists the proxy IPs or CIDRs allowed to forward the client IP,
+	// as "10.0.0.0/8,..." with no proxy trusted by default
+	TrustedProxies string `mapstructure:"TRUSTED_PROXIES"`
+	// APIClients lists the apps users can log in from, as "id:channel:secret,..."
+	APIClients string `mapstructure:"API_CLIENTS"`
+	// VoteBatchSize is the number of votes buffered by the consumer before a flush
+	VoteBatchSize int `mapstructure:"VOTE_BATCH_SIZE"`
+	// VoteFlushInterval is the longest time a consumed vote waits to be flushed
+	VoteFlushInterval time.Duration `mapstructure:"VOTE_FLUSH_INTERVAL"`
+	// VoteTallyFlushInterval is how often the votes counted in memory are added to the vote counters
+	VoteTallyFlushInterval time.Duration `mapstructure:"VOTE_TALLY_FLUSH_INTERVAL"`
+	// ResultStreamInterval is the shortest time between two result stream updates
+	ResultStreamInterval time.Duration `mapstructure:"RESULT_STREAM_INTERVAL"`
+	// SchedulerInterval is how often scheduled eliminations are opened and expired ones finished
+	SchedulerInterval time.Duration `mapstructure:"SCHEDULER_INTERVAL"`
+	// CloseDrainTimeout is the longest a closed elimination waits for its queued
+	// votes before its result is frozen
+	CloseDrainTimeout time.Duration `mapstructure:"CLOSE_DRAIN_TIMEOUT"`
+	// ChallengeBaseDifficulty and ChallengeMaxDifficulty bound the leading zero bits
+	// a vote challenge solution must have
+	ChallengeBaseDifficulty int `mapstructure:"CHALLENGE_BASE_DIFFICULTY"`
+	ChallengeMaxDifficulty  int `mapstructure:"CHALLENGE_MAX_DIFFICULTY"`
+	// ChallengeSecret is the key vote challenges are signed with, it must differ
+	// from ACCESS_TOKEN_SECRET
+	ChallengeSecret string `mapstructure:"CHALLENGE_SECRET"`
+	// ChallengeFreeRate is the number of challenges per ChallengeWindow that do not raise the difficulty
+	ChallengeFreeRate int           `mapstructure:"CHALLENGE_FREE_RATE"`
+	ChallengeWindow   time.Duration `mapstructure:"CHALLENGE_WINDOW"`
+	// ChallengeTTL is how long a vote challenge can be solved for
+	ChallengeTTL time.Duration `mapstructure:"CHALLENGE_TTL"`
+	// ReceiptSigningKey is the base64 encoded Ed25519 seed vote receipts and
+	// result certificates are signed with
+	ReceiptSigningKey string `mapstructure:"RECEIPT_SIGNING_KEY"`
+	// UnsignedVoteGrace is how long after startup the consumer still accepts
+	// votes queued before votes were signed, zero rejects them right away
+	UnsignedVoteGrace time.Duration `mapstructure:"UNSIGNED_VOTE_GRACE"`
+	// RateLimitBackend is where vote rate limits are kept, memory or postgres
+	RateLimitBackend string `mapstructure:"RATE_LIMIT_BACKEND"`
+	// The vote rate limits are token buckets refilled at Rate tokens per second
+	// up to Burst tokens, a zero rate disables the limit
+	// The elimination limit is always kept per replica, whatever the backend
+	RateLimitUserRate         float64 `mapstructure:"RATE_LIMIT_USER_RATE"`
+	RateLimitUserBurst        int     `mapstructure:"RATE_LIMIT_USER_BURST"`
+	RateLimitIPRate           float64 `mapstructure:"RATE_LIMIT_IP_RATE"`
+	RateLimitIPBurst          int     `mapstructure:"RATE_LIMIT_IP_BURST"`
+	RateLimitEliminationRate  float64 `mapstructure:"RATE_LIMIT_ELIMINATION_RATE"`
+	RateLimitEliminationBurst int     `mapstructure:"RATE_LIMIT_ELIMINATION_BURST"`
 }
 
 func NewEnv() (*Env, error) {
@@ -19,6 +70,26 @@ func NewEnv() (*Env, error) {
 	viper.AddConfigPath(".")
 	viper.AutomaticEnv()
 
+	viper.SetDefault("VOTE_BATCH_SIZE", 500)
+	viper.SetDefault("VOTE_FLUSH_INTERVAL", "250ms")
+	viper.SetDefault("VOTE_TALLY_FLUSH_INTERVAL", "1s")
+	viper.SetDefault("RESULT_STREAM_INTERVAL", "1s")
+	viper.SetDefault("SCHEDULER_INTERVAL", "5s")
+	viper.SetDefault("CLOSE_DRAIN_TIMEOUT", "1m")
+	viper.SetDefault("UNSIGNED_VOTE_GRACE", "0s")
+	viper.SetDefault("CHALLENGE_BASE_DIFFICULTY", 16)
+	viper.SetDefault("CHALLENGE_MAX_DIFFICULTY", 24)
+	viper.SetDefault("CHALLENGE_FREE_RATE", 10)
+	viper.SetDefault("CHALLENGE_WINDOW", "1m")
+	viper.SetDefault("CHALLENGE_TTL", "2m")
+	viper.SetDefault("RATE_LIMIT_BACKEND", "memory")
+	viper.SetDefault("RATE_LIMIT_USER_RATE", 1)
+	viper.SetDefault("RATE_LIMIT_USER_BURST", 10)
+	viper.SetDefault("RATE_LIMIT_IP_RATE", 20)
+	viper.SetDefault("RATE_LIMIT_IP_BURST", 100)
+	viper.SetDefault("RATE_LIMIT_ELIMINATION_RATE", 5000)
+	viper.SetDefault("RATE_LIMIT_ELIMINATION_BURST", 10000)
+
 	err := viper.ReadInConfig()
 	if err != nil {
 		return nil, err
@@ -29,5 +100,51 @@ func NewEnv() (*Env, error) {
 		return nil, err
 	}
 
+	err = env.validate()
+	if err != nil {
+		return nil, err
+	}
+
 	return &env, nil
 }
+
+// validate rejects values the server cannot safely start with
+func (e *Env) validate() error {
+	if e.ChallengeSecret == "" || e.ChallengeSecret == e.SecretKey {
+		return errors.New("CHALLENGE_SECRET must be set and differ from ACCESS_TOKEN_SECRET")
+	}
+	// Receipts and certificates are published, so their key must outlive any
+	// rotation of the JWT secret and not leak along with it
+	if e.ReceiptSigningKey == "" || e.ReceiptSigningKey == e.SecretKey {
+		return errors.New("RECEIPT_SIGNING_KEY must be set and differ from ACCESS_TOKEN_SECRET")
+	}
+	// A zero batch size would let the consumer prefetch without limit
+	if e.VoteBatchSize <= 0 {
+		return errors.New("VOTE_BATCH_SIZE must be greater than zero")
+	}
+	// The consumer checks for due batches every half of the flush interval
+	if e.VoteFlushInterval < 2*time.Millisecond {
+		return errors.New("VOTE_FLUSH_INTERVAL must be at least 2ms")
+	}
+	if e.UnsignedVoteGrace < 0 {
+		return errors.New("UNSIGNED_VOTE_GRACE must not be negative")
+	}
+
+	var intervals = []struct {
+		name  string
+		value time.Duration
+	}{
+		{"VOTE_TALLY_FLUSH_INTERVAL", e.VoteTallyFlushInterval},
+		{"RESULT_STREAM_INTERVAL", e.ResultStreamInterval},
+		{"SCHEDULER_INTERVAL", e.SchedulerInterval},
+		{"CHALLENGE_WINDOW", e.ChallengeWindow},
+		{"CHALLENGE_TTL", e.ChallengeTTL},
+	}
+	for _, i := range intervals {
+		if i.value <= 0 {
+			return fmt.Errorf("%s must be greater than zero", i.name)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,251 @@
+package challenge
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/bits"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	// ErrInvalid is returned when a challenge was not issued by this server or
+	// was issued to another user or resource
+	ErrInvalid = errors.New("invalid challenge")
+	// ErrExpired is returned when a challenge is past its expiration
+	ErrExpired = errors.New("challenge has expired")
+	// ErrUnsolved is returned when the solution does not meet the challenge difficulty
+	ErrUnsolved = errors.New("challenge solution does not meet the difficulty")
+	// ErrUsed is returned when a solved challenge is submitted a second time
+	ErrUsed = errors.New("challenge was already used")
+)
+
+// Challenge is a signed nonce the client must solve before a protected request
+// A solution is any string for which sha256(token + ":" + solution) starts with
+// at least Difficulty zero bits
+type Challenge struct {
+	Token      string    `json:"challenge"`
+	Difficulty int       `json:"difficulty"`
+	Expires    time.Time `json:"expires"`
+}
+
+type Options struct {
+	// BaseDifficulty is the difficulty of a client that is not voting fast
+	BaseDifficulty int
+	// MaxDifficulty caps the difficulty of the fastest clients
+	MaxDifficulty int
+	// FreeRate is the number of challenges issued in a window that do not raise the difficulty
+	FreeRate int
+	// Window is the period the request rate of a client is measured over
+	Window time.Duration
+	// TTL is how long a challenge can be solved for
+	TTL time.Duration
+}
+
+// Issuer signs challenges and verifies their solutions
+// Challenges are stateless until solved, the nonces of solved challenges are
+// stored in PostgreSQL so every replica rejects a reused one
+type Issuer struct {
+	nonces  nonceStore
+	secret  []byte
+	options Options
+	rate    *rateTracker
+}
+
+// nonceStore keeps the nonces of solved challenges until they expire
+type nonceStore interface {
+	// use records a nonce and reports whether it was not used before
+	use(ctx context.Context, nonce string, expires time.Time) (bool, error)
+	// prune drops the nonces past their expiration
+	prune(ctx context.Context) error
+}
+
+func NewIssuer(db *sqlx.DB, secret string, options Options) (*Issuer, error) {
+	if len(secret) < 32 {
+		return nil, errors.New("challenge secret must have at least 32 bytes")
+	}
+
+	return &Issuer{
+		nonces:  postgresNonces{db: db},
+		secret:  []byte(secret),
+		options: options,
+		rate:    newRateTracker(options.Window),
+	}, nil
+}
+
+// Start removes the expired nonces on every TTL until ctx is done
+func (i *Issuer) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(i.options.TTL)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("stopping challenge pruner")
+				return
+			case <-ticker.C:
+				if err := i.nonces.prune(ctx); err != nil {
+					slog.Error("failed to prune used challenges", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Issue returns a challenge bound to the subject and resource
+// Its difficulty grows with the number of challenges recently issued to the
+// subject and to the IP it comes from, one bit for every doubling above the
+// free rate, so challenges stockpiled ahead of time get harder as well
+func (i *Issuer) Issue(subject, resource, ip string) (*Challenge, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	difficulty := i.difficulty(max(i.rate.add("user:"+subject), i.rate.add("ip:"+ip)))
+	expires := time.Now().Add(i.options.TTL).Truncate(time.Second)
+
+	payload := strings.Join([]string{
+		hex.EncodeToString(nonce),
+		subject,
+		resource,
+		strconv.Itoa(difficulty),
+		strconv.FormatInt(expires.Unix(), 10),
+	}, "|")
+
+	return &Challenge{
+		Token:      encode([]byte(payload)) + "." + encode(i.sign(payload)),
+		Difficulty: difficulty,
+		Expires:    expires,
+	}, nil
+}
+
+// Verify checks the solution of a challenge issued to the subject and resource
+// and consumes it, so the same challenge cannot be used again
+func (i *Issuer) Verify(ctx context.Context, token, solution, subject, resource string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	encodedPayload, encodedSignature, ok := strings.Cut(token, ".")
+	if !ok {
+		return ErrInvalid
+	}
+	payload, err := decode(encodedPayload)
+	if err != nil {
+		return ErrInvalid
+	}
+	signature, err := decode(encodedSignature)
+	if err != nil || !hmac.Equal(signature, i.sign(string(payload))) {
+		return ErrInvalid
+	}
+
+	parts := strings.Split(string(payload), "|")
+	if len(parts) != 5 || parts[1] != subject || parts[2] != resource {
+		return ErrInvalid
+	}
+	difficulty, err := strconv.Atoi(parts[3])
+	if err != nil {
+		return ErrInvalid
+	}
+	unix, err := strconv.ParseInt(parts[4], 10, 64)
+	if err != nil {
+		return ErrInvalid
+	}
+	expires := time.Unix(unix, 0)
+	if time.Now().After(expires) {
+		return ErrExpired
+	}
+
+	hash := sha256.Sum256([]byte(token + ":" + solution))
+	if leadingZeros(hash[:]) < difficulty {
+		return ErrUnsolved
+	}
+
+	fresh, err := i.nonces.use(ctx, parts[0], expires)
+	if err != nil {
+		return err
+	}
+	if !fresh {
+		return ErrUsed
+	}
+
+	return nil
+}
+
+func (i *Issuer) difficulty(count int) int {
+	var extra int
+	if free := max(i.options.FreeRate, 1); count >= free {
+		extra = bits.Len(uint(count / free))
+	}
+	return min(i.options.BaseDifficulty+extra, i.options.MaxDifficulty)
+}
+
+func (i *Issuer) sign(payload string) []byte {
+	mac := hmac.New(sha256.New, i.secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// leadingZeros counts the zero bits at the start of hash
+func leadingZeros(hash []byte) int {
+	var n int
+	for _, b := range hash {
+		if b != 0 {
+			return n + bits.LeadingZeros8(b)
+		}
+		n += 8
+	}
+	return n
+}
+
+func encode(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decode(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
+
+// postgresNonces keeps the nonces in PostgreSQL, shared by every replica
+type postgresNonces struct {
+	db *sqlx.DB
+}
+
+func (p postgresNonces) use(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	var query = `
+		INSERT INTO used_challenges (nonce, expires)
+		VALUES ($1, $2)
+		ON CONFLICT (nonce) DO NOTHING
+	`
+
+	res, err := p.db.ExecContext(ctx, query, nonce, expires)
+	if err != nil {
+		return false, fmt.Errorf("failed to store used challenge: %w", err)
+	}
+	rows, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to get rows affected: %w", err)
+	}
+
+	return rows > 0, nil
+}
+
+func (p postgresNonces) prune(ctx context.Context) error {
+	_, err := p.db.ExecContext(ctx, "DELETE FROM used_challenges WHERE expires < now()")
+	if err != nil {
+		return fmt.Errorf("failed to prune used challenges: %w", err)
+	}
+
+	return nil
+}
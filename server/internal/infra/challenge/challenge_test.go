@@ -0,0 +1,326 @@
+package challenge
+
+import (
+	"context"
+	"crypto/sha256"
+	"errors"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memoryNonces keeps the used nonces in memory for the tests
+type memoryNonces struct {
+	mu   sync.Mutex
+	used map[string]time.Time
+}
+
+func (m *memoryNonces) use(ctx context.Context, nonce string, expires time.Time) (bool, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if _, ok := m.used[nonce]; ok {
+		return false, nil
+	}
+	m.used[nonce] = expires
+	return true, nil
+}
+
+func (m *memoryNonces) prune(ctx context.Context) error {
+	return nil
+}
+
+func newTestIssuer(options Options) *Issuer {
+	return &Issuer{
+		nonces:  &memoryNonces{used: make(map[string]time.Time)},
+		secret:  []byte(strings.Repeat("s", 32)),
+		options: options,
+		rate:    newRateTracker(options.Window),
+	}
+}
+
+// solve returns a solution of token, or one that fails its difficulty when solved is false
+func solve(t *testing.T, token string, difficulty int, solved bool) string {
+	t.Helper()
+
+	for n := 0; n < 1<<24; n++ {
+		solution := strconv.Itoa(n)
+		hash := sha256.Sum256([]byte(token + ":" + solution))
+		if (leadingZeros(hash[:]) >= difficulty) == solved {
+			return solution
+		}
+	}
+
+	t.Fatalf("no solution found for difficulty %d", difficulty)
+	return ""
+}
+
+func TestVerify(t *testing.T) {
+	ctx := context.Background()
+	options := Options{
+		BaseDifficulty: 8,
+		MaxDifficulty:  12,
+		FreeRate:       100,
+		Window:         time.Hour,
+		TTL:            time.Minute,
+	}
+
+	tamper := func(token string) string {
+		payload, signature, _ := strings.Cut(token, ".")
+		decoded, _ := decode(payload)
+		forged := strings.Replace(string(decoded), "user", "resu", 1)
+		return encode([]byte(forged)) + "." + signature
+	}
+
+	tests := []struct {
+		name     string
+		options  Options
+		token    func(token string) string
+		solved   bool
+		subject  string
+		resource string
+		want     error
+	}{
+		{
+			name:     "solved",
+			solved:   true,
+			subject:  "user",
+			resource: "resource",
+		},
+		{
+			name:     "unsolved",
+			solved:   false,
+			subject:  "user",
+			resource: "resource",
+			want:     ErrUnsolved,
+		},
+		{
+			name:     "expired",
+			options:  Options{BaseDifficulty: 8, MaxDifficulty: 12, FreeRate: 100, Window: time.Hour, TTL: -time.Minute},
+			solved:   true,
+			subject:  "user",
+			resource: "resource",
+			want:     ErrExpired,
+		},
+		{
+			name:     "wrong subject",
+			solved:   true,
+			subject:  "other",
+			resource: "resource",
+			want:     ErrInvalid,
+		},
+		{
+			name:     "wrong resource",
+			solved:   true,
+			subject:  "user",
+			resource: "other",
+			want:     ErrInvalid,
+		},
+		{
+			name:     "tampered payload",
+			token:    tamper,
+			solved:   true,
+			subject:  "resu",
+			resource: "resource",
+			want:     ErrInvalid,
+		},
+		{
+			name: "tampered signature",
+			token: func(token string) string {
+				payload, signature, _ := strings.Cut(token, ".")
+				decoded, _ := decode(signature)
+				decoded[0] ^= 0xff
+				return payload + "." + encode(decoded)
+			},
+			solved:   true,
+			subject:  "user",
+			resource: "resource",
+			want:     ErrInvalid,
+		},
+		{
+			name:     "malformed",
+			token:    func(token string) string { return strings.ReplaceAll(token, ".", "") },
+			solved:   true,
+			subject:  "user",
+			resource: "resource",
+			want:     ErrInvalid,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.options == (Options{}) {
+				tt.options = options
+			}
+			issuer := newTestIssuer(tt.options)
+
+			c, err := issuer.Issue("user", "resource", "127.0.0.1")
+			if err != nil {
+				t.Fatalf("Issue() error = %v", err)
+			}
+			token := c.Token
+			if tt.token != nil {
+				token = tt.token(token)
+			}
+			solution := solve(t, token, c.Difficulty, tt.solved)
+
+			err = issuer.Verify(ctx, token, solution, tt.subject, tt.resource)
+			if !errors.Is(err, tt.want) {
+				t.Errorf("Verify() error = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyReused(t *testing.T) {
+	ctx := context.Background()
+	issuer := newTestIssuer(Options{
+		BaseDifficulty: 8,
+		MaxDifficulty:  12,
+		FreeRate:       100,
+		Window:         time.Hour,
+		TTL:            time.Minute,
+	})
+
+	c, err := issuer.Issue("user", "resource", "127.0.0.1")
+	if err != nil {
+		t.Fatalf("Issue() error = %v", err)
+	}
+	solution := solve(t, c.Token, c.Difficulty, true)
+
+	if err := issuer.Verify(ctx, c.Token, solution, "user", "resource"); err != nil {
+		t.Fatalf("first Verify() error = %v", err)
+	}
+	if err := issuer.Verify(ctx, c.Token, solution, "user", "resource"); !errors.Is(err, ErrUsed) {
+		t.Errorf("second Verify() error = %v, want %v", err, ErrUsed)
+	}
+}
+
+func TestDifficulty(t *testing.T) {
+	tests := []struct {
+		name     string
+		freeRate int
+		count    int
+		want     int
+	}{
+		{name: "no requests", freeRate: 10, count: 0, want: 16},
+		{name: "below the free rate", freeRate: 10, count: 9, want: 16},
+		{name: "at the free rate", freeRate: 10, count: 10, want: 17},
+		{name: "below twice the free rate", freeRate: 10, count: 19, want: 17},
+		{name: "twice the free rate", freeRate: 10, count: 20, want: 18},
+		{name: "four times the free rate", freeRate: 10, count: 40, want: 19},
+		{name: "capped at the max difficulty", freeRate: 10, count: 10000, want: 20},
+		{name: "zero free rate counts every request", freeRate: 0, count: 1, want: 17},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			issuer := newTestIssuer(Options{
+				BaseDifficulty: 16,
+				MaxDifficulty:  20,
+				FreeRate:       tt.freeRate,
+				Window:         time.Minute,
+			})
+
+			if got := issuer.difficulty(tt.count); got != tt.want {
+				t.Errorf("difficulty(%d) = %d, want %d", tt.count, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIssueRaisesDifficulty(t *testing.T) {
+	issuer := newTestIssuer(Options{
+		BaseDifficulty: 8,
+		MaxDifficulty:  20,
+		FreeRate:       2,
+		Window:         time.Hour,
+		TTL:            time.Minute,
+	})
+
+	var got []int
+	for range 5 {
+		c, err := issuer.Issue("user", "resource", "127.0.0.1")
+		if err != nil {
+			t.Fatalf("Issue() error = %v", err)
+		}
+		got = append(got, c.Difficulty)
+	}
+
+	// The challenges issued before count, solved or not
+	want := []int{8, 8, 9, 9, 10}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("difficulties = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestRateTrackerAdd(t *testing.T) {
+	tracker := newRateTracker(time.Hour)
+
+	for want := range 3 {
+		if got := tracker.add("a"); got != want {
+			t.Errorf("add(a) = %d, want %d", got, want)
+		}
+	}
+	if got := tracker.add("b"); got != 0 {
+		t.Errorf("add(b) = %d, want 0", got)
+	}
+}
+
+func TestRateTrackerEstimate(t *testing.T) {
+	var start = time.Date(2025, 4, 8, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		elapsed time.Duration
+		want    int
+	}{
+		{name: "start of the window counts the whole previous window", elapsed: 0, want: 12},
+		{name: "a quarter into the window", elapsed: 15 * time.Second, want: 9},
+		{name: "half into the window", elapsed: 30 * time.Second, want: 7},
+		{name: "end of the window counts the current window only", elapsed: time.Minute - time.Nanosecond, want: 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newRateTracker(time.Minute)
+			w := &window{start: start, current: 2, previous: 10}
+
+			if got := tracker.estimate(w, start.Add(tt.elapsed)); got != tt.want {
+				t.Errorf("estimate() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateTrackerRoll(t *testing.T) {
+	var start = time.Date(2025, 4, 8, 20, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name         string
+		now          time.Time
+		wantCurrent  int
+		wantPrevious int
+	}{
+		{name: "same window", now: start.Add(30 * time.Second), wantCurrent: 2, wantPrevious: 10},
+		{name: "next window", now: start.Add(90 * time.Second), wantCurrent: 0, wantPrevious: 2},
+		{name: "two windows later", now: start.Add(150 * time.Second), wantCurrent: 0, wantPrevious: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tracker := newRateTracker(time.Minute)
+			w := &window{start: start, current: 2, previous: 10}
+
+			tracker.roll(w, tt.now)
+			if w.current != tt.wantCurrent || w.previous != tt.wantPrevious {
+				t.Errorf("roll() = current %d previous %d, want current %d previous %d",
+					w.current, w.previous, tt.wantCurrent, tt.wantPrevious)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package challenge
+
+import (
+	"sync"
+	"time"
+)
+
+type window struct {
+	start    time.Time
+	current  int
+	previous int
+}
+
+// rateTracker approximates how many requests each key made in the last window
+// by weighting the previous fixed window by how much of it still overlaps
+// Rates are kept per replica, which is enough to scale the difficulty
+type rateTracker struct {
+	mu      sync.Mutex
+	size    time.Duration
+	windows map[string]*window
+	pruned  time.Time
+}
+
+func newRateTracker(size time.Duration) *rateTracker {
+	return &rateTracker{
+		size:    size,
+		windows: make(map[string]*window),
+		pruned:  time.Now(),
+	}
+}
+
+// add counts a request of key and returns how many it made in the last
+// window before this one
+func (t *rateTracker) add(key string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	now := time.Now()
+	t.prune(now)
+
+	w, ok := t.windows[key]
+	if !ok {
+		w = &window{start: now.Truncate(t.size)}
+		t.windows[key] = w
+	}
+	t.roll(w, now)
+
+	count := t.estimate(w, now)
+	w.current++
+	return count
+}
+
+// estimate weights the previous window of w by how much of it still overlaps
+// the last window
+func (t *rateTracker) estimate(w *window, now time.Time) int {
+	overlap := 1 - float64(now.Sub(w.start))/float64(t.size)
+	return w.current + int(float64(w.previous)*overlap)
+}
+
+// roll moves w forward so its current window contains now
+func (t *rateTracker) roll(w *window, now time.Time) {
+	start := now.Truncate(t.size)
+	switch {
+	case start.Equal(w.start):
+		return
+	case start.Sub(w.start) == t.size:
+		w.previous = w.current
+	default:
+		w.previous = 0
+	}
+	w.current = 0
+	w.start = start
+}
+
+// prune drops the keys without requests in the last two windows
+func (t *rateTracker) prune(now time.Time) {
+	if now.Sub(t.pruned) < t.size {
+		return
+	}
+	for key, w := range t.windows {
+		if now.Sub(w.start) >= 2*t.size {
+			delete(t.windows, key)
+		}
+	}
+	t.pruned = now
+}
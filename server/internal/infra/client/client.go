@@ -0,0 +1,84 @@
+package client
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// Channel is the origin a vote was cast from
+type Channel string
+
+const (
+	ChannelWeb      Channel = "web"
+	ChannelApp      Channel = "app"
+	ChannelTVRemote Channel = "tv_remote"
+	ChannelPartner  Channel = "partner"
+)
+
+// DefaultChannel is used when a user logs in without client credentials
+const DefaultChannel = ChannelWeb
+
+// ErrInvalidCredentials is returned when a client ID or secret does not match
+var ErrInvalidCredentials = errors.New("invalid client credentials")
+
+// IsValid reports whether c is a known channel
+func (c Channel) IsValid() bool {
+	switch c {
+	case ChannelWeb, ChannelApp, ChannelTVRemote, ChannelPartner:
+		return true
+	default:
+		return false
+	}
+}
+
+type client struct {
+	channel Channel
+	secret  string
+}
+
+// Registry holds the API clients allowed to authenticate users
+type Registry struct {
+	clients map[string]client
+}
+
+// NewRegistry parses the client list in the format "id:channel:secret,..."
+func NewRegistry(spec string) (*Registry, error) {
+	r := &Registry{clients: make(map[string]client)}
+
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		parts := strings.SplitN(entry, ":", 3)
+		if len(parts) != 3 || parts[0] == "" || parts[2] == "" {
+			return nil, fmt.Errorf("invalid client entry %q", entry)
+		}
+
+		channel := Channel(parts[1])
+		if !channel.IsValid() {
+			return nil, fmt.Errorf("invalid channel %q for client %s", channel, parts[0])
+		}
+		r.clients[parts[0]] = client{channel: channel, secret: parts[2]}
+	}
+
+	return r, nil
+}
+
+// Authenticate checks the client credentials and returns the channel of the client
+// Without a client ID the user is assumed to be on the default channel
+func (r *Registry) Authenticate(id, secret string) (Channel, error) {
+	if id == "" {
+		return DefaultChannel, nil
+	}
+
+	c, ok := r.clients[id]
+	if !ok || subtle.ConstantTimeCompare([]byte(c.secret), []byte(secret)) != 1 {
+		return "", ErrInvalidCredentials
+	}
+
+	return c.channel, nil
+}
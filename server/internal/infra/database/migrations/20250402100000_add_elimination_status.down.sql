@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "idx_eliminations_status";
+
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "status";
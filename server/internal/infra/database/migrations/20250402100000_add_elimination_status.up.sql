@@ -0,0 +1,6 @@
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "status" varchar(255) NOT NULL DEFAULT 'open';
+
+UPDATE "eliminations" SET "status" = 'closed' WHERE "open" = false;
+
+CREATE INDEX "idx_eliminations_status" ON eliminations ("status");
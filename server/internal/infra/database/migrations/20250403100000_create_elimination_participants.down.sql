@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS "idx_elimination_participants_participant";
+
+ALTER TABLE "elimination_participants"
+	DROP CONSTRAINT IF EXISTS "fk_elimination_participants_elimination_id",
+	DROP CONSTRAINT IF EXISTS "fk_elimination_participants_participant_id";
+
+DROP TABLE IF EXISTS "elimination_participants";
@@ -0,0 +1,29 @@
+CREATE TABLE IF NOT EXISTS "elimination_participants" (
+	"elimination_id" varchar(255) NOT NULL,
+	"participant_id" varchar(255) NOT NULL,
+	"created" timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY ("elimination_id", "participant_id")
+);
+
+ALTER TABLE "elimination_participants"
+	ADD CONSTRAINT "fk_elimination_participants_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "elimination_participants"
+	ADD CONSTRAINT "fk_elimination_participants_participant_id" FOREIGN KEY ("participant_id") REFERENCES "participants" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "idx_elimination_participants_participant" ON elimination_participants ("participant_id");
+
+-- Backfill the current members of every elimination
+INSERT INTO "elimination_participants" ("elimination_id", "participant_id", "created")
+SELECT "elimination_id", "id", "updated"
+FROM "participants"
+WHERE "elimination_id" IS NOT NULL
+ON CONFLICT DO NOTHING;
+
+-- Closed eliminations no longer point to their participants, so their
+-- members are recovered from the votes they received
+INSERT INTO "elimination_participants" ("elimination_id", "participant_id", "created")
+SELECT "elimination_id", "participant_id", MIN("created")
+FROM "votes"
+GROUP BY "elimination_id", "participant_id"
+ON CONFLICT DO NOTHING;
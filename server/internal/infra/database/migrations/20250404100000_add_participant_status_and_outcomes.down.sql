@@ -0,0 +1,10 @@
+ALTER TABLE "elimination_outcomes"
+	DROP CONSTRAINT IF EXISTS "fk_elimination_outcomes_elimination_id",
+	DROP CONSTRAINT IF EXISTS "fk_elimination_outcomes_participant_id";
+
+DROP TABLE IF EXISTS "elimination_outcomes";
+
+DROP INDEX IF EXISTS "idx_participants_status";
+
+ALTER TABLE "participants"
+	DROP COLUMN IF EXISTS "status";
@@ -0,0 +1,22 @@
+ALTER TABLE "participants"
+	ADD COLUMN IF NOT EXISTS "status" varchar(255) NOT NULL DEFAULT 'active';
+
+UPDATE "participants" SET "status" = 'in_elimination' WHERE "elimination_id" IS NOT NULL;
+
+CREATE INDEX "idx_participants_status" ON participants ("status");
+
+CREATE TABLE IF NOT EXISTS "elimination_outcomes" (
+	"elimination_id" varchar(255) NOT NULL,
+	"participant_id" varchar(255) NOT NULL,
+	"votes" integer NOT NULL DEFAULT 0,
+	"percentage" numeric(5, 2) NOT NULL DEFAULT 0,
+	"eliminated" boolean NOT NULL DEFAULT FALSE,
+	"created" timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY ("elimination_id", "participant_id")
+);
+
+ALTER TABLE "elimination_outcomes"
+	ADD CONSTRAINT "fk_elimination_outcomes_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "elimination_outcomes"
+	ADD CONSTRAINT "fk_elimination_outcomes_participant_id" FOREIGN KEY ("participant_id") REFERENCES "participants" ("id") ON DELETE CASCADE;
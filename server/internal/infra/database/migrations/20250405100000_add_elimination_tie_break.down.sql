@@ -0,0 +1,7 @@
+ALTER TABLE "elimination_outcomes"
+	DROP COLUMN IF EXISTS "tied";
+
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "tie_break_policy",
+	DROP COLUMN IF EXISTS "tie_break_priority",
+	DROP COLUMN IF EXISTS "tie_break_decision";
@@ -0,0 +1,7 @@
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "tie_break_policy" varchar(255) NOT NULL DEFAULT 'earliest_vote',
+	ADD COLUMN IF NOT EXISTS "tie_break_priority" text[] NOT NULL DEFAULT '{}',
+	ADD COLUMN IF NOT EXISTS "tie_break_decision" varchar(255) NULL;
+
+ALTER TABLE "elimination_outcomes"
+	ADD COLUMN IF NOT EXISTS "tied" boolean NOT NULL DEFAULT FALSE;
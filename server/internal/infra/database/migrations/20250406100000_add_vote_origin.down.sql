@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS "idx_votes_elimination_origin";
+
+ALTER TABLE "votes"
+	DROP COLUMN IF EXISTS "origin";
@@ -0,0 +1,4 @@
+ALTER TABLE "votes"
+	ADD COLUMN IF NOT EXISTS "origin" varchar(255) NOT NULL DEFAULT 'web';
+
+CREATE INDEX "idx_votes_elimination_origin" ON votes ("elimination_id", "origin");
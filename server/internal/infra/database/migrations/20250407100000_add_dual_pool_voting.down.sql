@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS "idx_votes_unique_verified";
+
+ALTER TABLE "votes"
+	DROP COLUMN IF EXISTS "pool";
+
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "voting_scheme",
+	DROP COLUMN IF EXISTS "verified_weight",
+	DROP COLUMN IF EXISTS "fan_weight";
+
+ALTER TABLE "users"
+	DROP COLUMN IF EXISTS "verified";
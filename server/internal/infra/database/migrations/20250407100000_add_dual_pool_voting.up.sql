@@ -0,0 +1,12 @@
+ALTER TABLE "users"
+	ADD COLUMN IF NOT EXISTS "verified" boolean NOT NULL DEFAULT false;
+
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "voting_scheme" varchar(255) NOT NULL DEFAULT 'single',
+	ADD COLUMN IF NOT EXISTS "verified_weight" integer NOT NULL DEFAULT 0,
+	ADD COLUMN IF NOT EXISTS "fan_weight" integer NOT NULL DEFAULT 0;
+
+ALTER TABLE "votes"
+	ADD COLUMN IF NOT EXISTS "pool" varchar(255) NOT NULL DEFAULT 'fan';
+
+CREATE UNIQUE INDEX "idx_votes_unique_verified" ON votes ("elimination_id", "user_id") WHERE "pool" = 'verified';
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS "used_challenges" (
+	"nonce" varchar(255) PRIMARY KEY NOT NULL,
+	"expires" timestamptz NOT NULL
+);
+
+CREATE INDEX "idx_used_challenges_expires" ON used_challenges ("expires");
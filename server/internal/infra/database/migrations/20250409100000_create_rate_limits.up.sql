@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS "rate_limits" (
+	"key" varchar(255) PRIMARY KEY NOT NULL,
+	"tokens" double precision NOT NULL,
+	"updated" timestamptz NOT NULL DEFAULT now()
+);
+
+CREATE INDEX "idx_rate_limits_updated" ON rate_limits ("updated");
@@ -0,0 +1,5 @@
+DROP TABLE IF EXISTS "elimination_snapshots";
+
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "closed_at",
+	DROP COLUMN IF EXISTS "drained_at";
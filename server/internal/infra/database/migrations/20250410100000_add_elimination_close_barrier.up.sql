@@ -0,0 +1,12 @@
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "closed_at" timestamptz NULL,
+	ADD COLUMN IF NOT EXISTS "drained_at" timestamptz NULL;
+
+CREATE TABLE IF NOT EXISTS "elimination_snapshots" (
+	"elimination_id" varchar(255) PRIMARY KEY NOT NULL,
+	"results" jsonb NOT NULL,
+	"created" timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "elimination_snapshots"
+	ADD CONSTRAINT "fk_elimination_snapshots_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS "vote_rejections" (
+	"vote_id" varchar(255) PRIMARY KEY NOT NULL,
+	"user_id" varchar(255) NOT NULL,
+	"elimination_id" varchar(255) NOT NULL,
+	"code" varchar(255) NOT NULL,
+	"reason" text NOT NULL,
+	"created" timestamptz NOT NULL DEFAULT now()
+);
@@ -0,0 +1,3 @@
+DROP TABLE IF EXISTS "vote_ledger";
+
+DROP FUNCTION IF EXISTS "reject_vote_ledger_change";
@@ -0,0 +1,23 @@
+CREATE TABLE IF NOT EXISTS "vote_ledger" (
+	"elimination_id" varchar(255) NOT NULL,
+	"seq" bigint NOT NULL,
+	"vote_ids" text[] NOT NULL,
+	"prev_hash" varchar(64) NOT NULL,
+	"hash" varchar(64) NOT NULL,
+	"created" timestamptz NOT NULL DEFAULT now(),
+	PRIMARY KEY ("elimination_id", "seq")
+);
+
+ALTER TABLE "vote_ledger"
+	ADD CONSTRAINT "fk_vote_ledger_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+-- The ledger is append-only, entries can never be changed once written
+CREATE OR REPLACE FUNCTION "reject_vote_ledger_change"() RETURNS trigger AS $$
+BEGIN
+	RAISE EXCEPTION 'vote_ledger is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER "vote_ledger_append_only"
+	BEFORE UPDATE ON "vote_ledger"
+	FOR EACH ROW EXECUTE FUNCTION "reject_vote_ledger_change"();
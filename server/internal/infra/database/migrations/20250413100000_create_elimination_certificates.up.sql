@@ -0,0 +1,10 @@
+-- The body is kept as the exact bytes that were signed
+CREATE TABLE IF NOT EXISTS "elimination_certificates" (
+	"elimination_id" varchar(255) PRIMARY KEY NOT NULL,
+	"body" text NOT NULL,
+	"signature" varchar(255) NOT NULL,
+	"created" timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "elimination_certificates"
+	ADD CONSTRAINT "fk_elimination_certificates_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
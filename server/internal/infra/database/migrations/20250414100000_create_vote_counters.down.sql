@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS "idx_votes_elimination_created";
+
+DROP TABLE IF EXISTS "elimination_voters";
+
+DROP TABLE IF EXISTS "vote_counters";
@@ -0,0 +1,37 @@
+-- Votes are counted per minute bucket, a result sums the buckets instead of
+-- scanning the votes table
+CREATE TABLE IF NOT EXISTS "vote_counters" (
+	"elimination_id" varchar(255) NOT NULL,
+	"participant_id" varchar(255) NOT NULL,
+	"origin" varchar(255) NOT NULL,
+	"pool" varchar(255) NOT NULL,
+	"bucket" timestamptz NOT NULL,
+	"count" bigint NOT NULL DEFAULT 0,
+	"reached_at" timestamptz NOT NULL,
+	PRIMARY KEY ("elimination_id", "participant_id", "origin", "pool", "bucket")
+);
+
+ALTER TABLE "vote_counters"
+	ADD CONSTRAINT "fk_vote_counters_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+-- The distinct voters of each elimination
+CREATE TABLE IF NOT EXISTS "elimination_voters" (
+	"elimination_id" varchar(255) NOT NULL,
+	"user_id" varchar(255) NOT NULL,
+	PRIMARY KEY ("elimination_id", "user_id")
+);
+
+ALTER TABLE "elimination_voters"
+	ADD CONSTRAINT "fk_elimination_voters_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+-- The votes of the close bucket are still counted from the votes table
+CREATE INDEX "idx_votes_elimination_created" ON votes ("elimination_id", "created");
+
+INSERT INTO "vote_counters" ("elimination_id", "participant_id", "origin", "pool", "bucket", "count", "reached_at")
+SELECT elimination_id, participant_id, origin, pool, date_trunc('minute', created), COUNT(id), MAX(created)
+FROM votes
+GROUP BY elimination_id, participant_id, origin, pool, date_trunc('minute', created);
+
+INSERT INTO "elimination_voters" ("elimination_id", "user_id")
+SELECT DISTINCT elimination_id, user_id
+FROM votes;
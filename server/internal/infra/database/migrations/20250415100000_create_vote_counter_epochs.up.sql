@@ -0,0 +1,10 @@
+-- A rebuild of the counters of an elimination starts a new epoch, the deltas
+-- tallied in memory during an older epoch are already in the rebuilt counters
+-- and are dropped when flushed
+CREATE TABLE IF NOT EXISTS "vote_counter_epochs" (
+	"elimination_id" varchar(255) PRIMARY KEY NOT NULL,
+	"epoch" bigint NOT NULL DEFAULT 0
+);
+
+ALTER TABLE "vote_counter_epochs"
+	ADD CONSTRAINT "fk_vote_counter_epochs_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
@@ -0,0 +1,20 @@
+ALTER TABLE "participants"
+	ADD COLUMN IF NOT EXISTS "elimination_id" varchar(255) NULL;
+
+UPDATE "participants" p SET "elimination_id" = (
+	SELECT ep."elimination_id"
+	FROM "elimination_participants" ep
+	JOIN "eliminations" e ON e."id" = ep."elimination_id"
+	WHERE ep."participant_id" = p."id" AND e."type" = 'elimination' AND e."status" <> 'closed'
+	ORDER BY e."created" DESC
+	LIMIT 1
+);
+
+ALTER TABLE "participants"
+	ADD CONSTRAINT "fk_participants_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+ALTER TABLE "elimination_outcomes"
+	DROP COLUMN IF EXISTS "winner";
+
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "type";
@@ -0,0 +1,12 @@
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "type" varchar(255) NOT NULL DEFAULT 'elimination';
+
+ALTER TABLE "elimination_outcomes"
+	ADD COLUMN IF NOT EXISTS "winner" boolean NOT NULL DEFAULT false;
+
+-- Membership lives in elimination_participants, a participant can be in several polls at once
+ALTER TABLE "participants"
+	DROP CONSTRAINT IF EXISTS "fk_participants_elimination_id";
+
+ALTER TABLE "participants"
+	DROP COLUMN IF EXISTS "elimination_id";
@@ -0,0 +1,2 @@
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "vote_mode";
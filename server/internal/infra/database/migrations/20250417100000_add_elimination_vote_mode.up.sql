@@ -0,0 +1,2 @@
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "vote_mode" varchar(255) NOT NULL DEFAULT 'eliminate';
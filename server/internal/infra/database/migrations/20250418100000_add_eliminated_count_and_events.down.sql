@@ -0,0 +1,12 @@
+DROP INDEX IF EXISTS "idx_elimination_events_elimination";
+
+ALTER TABLE "elimination_events"
+	DROP CONSTRAINT IF EXISTS "fk_elimination_events_elimination_id";
+
+DROP TABLE IF EXISTS "elimination_events";
+
+ALTER TABLE "elimination_participants"
+	DROP COLUMN IF EXISTS "removed_at";
+
+ALTER TABLE "eliminations"
+	DROP COLUMN IF EXISTS "eliminated_count";
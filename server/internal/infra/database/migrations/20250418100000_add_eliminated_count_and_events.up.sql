@@ -0,0 +1,21 @@
+ALTER TABLE "eliminations"
+	ADD COLUMN IF NOT EXISTS "eliminated_count" integer NOT NULL DEFAULT 1;
+
+-- A removed participant keeps their votes, which are no longer counted
+ALTER TABLE "elimination_participants"
+	ADD COLUMN IF NOT EXISTS "removed_at" timestamptz NULL;
+
+CREATE TABLE IF NOT EXISTS "elimination_events" (
+	"id" varchar(255) PRIMARY KEY NOT NULL,
+	"elimination_id" varchar(255) NOT NULL,
+	"type" varchar(255) NOT NULL,
+	"participant_id" varchar(255) NULL,
+	"actor_id" varchar(255) NOT NULL,
+	"data" text NOT NULL,
+	"created" timestamptz NOT NULL DEFAULT now()
+);
+
+ALTER TABLE "elimination_events"
+	ADD CONSTRAINT "fk_elimination_events_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+CREATE INDEX "idx_elimination_events_elimination" ON elimination_events ("elimination_id", "created");
@@ -0,0 +1,2 @@
+ALTER TABLE "users"
+	ADD COLUMN IF NOT EXISTS "role" varchar(255) NOT NULL DEFAULT 'user';
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS "elimination_merkle_nodes";
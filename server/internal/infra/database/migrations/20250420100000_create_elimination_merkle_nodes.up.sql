@@ -0,0 +1,16 @@
+-- Every level of the Merkle tree of a certificate, stored when the elimination
+-- is finalized so inclusion proofs are served without rebuilding the tree
+-- Leaves keep the ID of their vote
+CREATE TABLE IF NOT EXISTS "elimination_merkle_nodes" (
+	"elimination_id" varchar(255) NOT NULL,
+	"level" integer NOT NULL,
+	"position" integer NOT NULL,
+	"hash" bytea NOT NULL,
+	"vote_id" varchar(255),
+	PRIMARY KEY ("elimination_id", "level", "position")
+);
+
+ALTER TABLE "elimination_merkle_nodes"
+	ADD CONSTRAINT "fk_elimination_merkle_nodes_elimination_id" FOREIGN KEY ("elimination_id") REFERENCES "eliminations" ("id") ON DELETE CASCADE;
+
+CREATE UNIQUE INDEX "idx_elimination_merkle_nodes_vote_id" ON elimination_merkle_nodes ("vote_id") WHERE "vote_id" IS NOT NULL;
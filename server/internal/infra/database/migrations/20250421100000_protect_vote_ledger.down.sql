@@ -0,0 +1,6 @@
+DROP TRIGGER IF EXISTS "vote_ledger_no_truncate" ON "vote_ledger";
+DROP TRIGGER IF EXISTS "vote_ledger_append_only" ON "vote_ledger";
+
+CREATE TRIGGER "vote_ledger_append_only"
+	BEFORE UPDATE ON "vote_ledger"
+	FOR EACH ROW EXECUTE FUNCTION "reject_vote_ledger_change"();
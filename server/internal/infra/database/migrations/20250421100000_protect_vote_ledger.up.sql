@@ -0,0 +1,10 @@
+-- Entries can neither be changed nor removed, one by one or all at once
+DROP TRIGGER IF EXISTS "vote_ledger_append_only" ON "vote_ledger";
+
+CREATE TRIGGER "vote_ledger_append_only"
+	BEFORE UPDATE OR DELETE ON "vote_ledger"
+	FOR EACH ROW EXECUTE FUNCTION "reject_vote_ledger_change"();
+
+CREATE TRIGGER "vote_ledger_no_truncate"
+	BEFORE TRUNCATE ON "vote_ledger"
+	FOR EACH STATEMENT EXECUTE FUNCTION "reject_vote_ledger_change"();
@@ -45,3 +45,20 @@ func (m middleware) WithAuth(next http.Handler) http.Handler {
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
+
+// WithAdmin only lets requests of admins through, it must run after WithAuth
+func (m middleware) WithAdmin(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		claims, ok := r.Context().Value(AuthKey{}).(*token.Claims)
+		if !ok {
+			errs.HttpError(w, errs.NewUnauthorizedError("invalid and/or expired token", nil))
+			return
+		}
+		if !claims.IsAdmin() {
+			errs.HttpError(w, errs.NewForbiddenError("admin role is required", errs.AdminRequired, nil))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// NewRealIP returns a middleware that sets the remote address of a request to
+// the client IP forwarded by a trusted proxy
+// trusted is a comma separated list of proxy IPs or CIDRs, the forwarding
+// headers of any other peer are ignored so clients cannot choose their own IP
+func NewRealIP(trusted string) (func(http.Handler) http.Handler, error) {
+	var proxies []netip.Prefix
+	for _, entry := range strings.Split(trusted, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		if !strings.Contains(entry, "/") {
+			addr, err := netip.ParseAddr(entry)
+			if err != nil {
+				return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+			}
+			proxies = append(proxies, netip.PrefixFrom(addr.Unmap(), addr.Unmap().BitLen()))
+			continue
+		}
+		prefix, err := netip.ParsePrefix(entry)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", entry, err)
+		}
+		proxies = append(proxies, prefix.Masked())
+	}
+
+	isTrusted := func(ip string) bool {
+		addr, err := netip.ParseAddr(strings.TrimSpace(ip))
+		if err != nil {
+			return false
+		}
+		addr = addr.Unmap()
+		for _, p := range proxies {
+			if p.Contains(addr) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			host, _, err := net.SplitHostPort(r.RemoteAddr)
+			if err != nil {
+				host = r.RemoteAddr
+			}
+			if len(proxies) == 0 || !isTrusted(host) {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			// Every proxy appends the peer it received the request from, so the
+			// client is the last hop that is not one of the trusted proxies
+			var client string
+			hops := strings.Split(r.Header.Get("X-Forwarded-For"), ",")
+			for i := len(hops) - 1; i >= 0; i-- {
+				hop := strings.TrimSpace(hops[i])
+				if _, err := netip.ParseAddr(hop); err != nil {
+					break
+				}
+				client = hop
+				if !isTrusted(hop) {
+					break
+				}
+			}
+			if client == "" {
+				if ip := strings.TrimSpace(r.Header.Get("X-Real-IP")); net.ParseIP(ip) != nil {
+					client = ip
+				}
+			}
+			if client != "" {
+				r.RemoteAddr = client
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}, nil
+}
@@ -0,0 +1,78 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// pruneInterval is how often the memory limiter drops buckets that are full again
+const pruneInterval = time.Minute
+
+type bucket struct {
+	tokens  float64
+	updated time.Time
+	limit   Limit
+}
+
+// memoryLimiter keeps the buckets of this replica in memory
+type memoryLimiter struct {
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	pruned  time.Time
+}
+
+func NewMemoryLimiter() Limiter {
+	return &memoryLimiter{
+		buckets: make(map[string]*bucket),
+		pruned:  time.Now(),
+	}
+}
+
+func (m *memoryLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	m.prune(now)
+
+	b, ok := m.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), updated: now}
+		m.buckets[key] = b
+	}
+	b.tokens = refill(b.tokens, b.updated, now, limit)
+	b.updated = now
+	b.limit = limit
+
+	if b.tokens < 1 {
+		return Result{RetryAfter: retryAfter(b.tokens, limit)}, nil
+	}
+	b.tokens--
+
+	return Result{Allowed: true}, nil
+}
+
+func (m *memoryLimiter) Refund(ctx context.Context, key string, limit Limit) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if b, ok := m.buckets[key]; ok {
+		b.tokens = min(b.tokens+1, float64(limit.Burst))
+	}
+
+	return nil
+}
+
+// prune drops the buckets that refilled completely, which behave like new ones
+func (m *memoryLimiter) prune(now time.Time) {
+	if now.Sub(m.pruned) < pruneInterval {
+		return
+	}
+	for key, b := range m.buckets {
+		if refill(b.tokens, b.updated, now, b.limit) >= float64(b.limit.Burst) {
+			delete(m.buckets, key)
+		}
+	}
+	m.pruned = now
+}
@@ -0,0 +1,180 @@
+package ratelimit
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestRefill(t *testing.T) {
+	var now = time.Date(2025, 4, 9, 20, 0, 0, 0, time.UTC)
+	var limit = Limit{Rate: 2, Burst: 10}
+
+	tests := []struct {
+		name    string
+		tokens  float64
+		elapsed time.Duration
+		want    float64
+	}{
+		{name: "no time elapsed", tokens: 3, elapsed: 0, want: 3},
+		{name: "refilled at the rate", tokens: 3, elapsed: 2 * time.Second, want: 7},
+		{name: "partial token", tokens: 0, elapsed: 250 * time.Millisecond, want: 0.5},
+		{name: "capped at the burst", tokens: 3, elapsed: time.Minute, want: 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := refill(tt.tokens, now.Add(-tt.elapsed), now, limit); got != tt.want {
+				t.Errorf("refill() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	tests := []struct {
+		name   string
+		tokens float64
+		limit  Limit
+		want   time.Duration
+	}{
+		{name: "empty bucket", tokens: 0, limit: Limit{Rate: 1, Burst: 1}, want: time.Second},
+		{name: "half a token", tokens: 0.5, limit: Limit{Rate: 1, Burst: 1}, want: 500 * time.Millisecond},
+		{name: "faster rate", tokens: 0, limit: Limit{Rate: 4, Burst: 1}, want: 250 * time.Millisecond},
+		{name: "rounded up to the millisecond", tokens: 0, limit: Limit{Rate: 3, Burst: 1}, want: 334 * time.Millisecond},
+		{name: "token available", tokens: 1, limit: Limit{Rate: 1, Burst: 1}, want: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := retryAfter(tt.tokens, tt.limit); got != tt.want {
+				t.Errorf("retryAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryLimiterBurst(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryLimiter()
+	// Slow enough that no token is refilled while the test runs
+	limit := Limit{Rate: 0.001, Burst: 3}
+
+	for i := range limit.Burst {
+		res, err := limiter.Allow(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if !res.Allowed {
+			t.Fatalf("Allow() %d was rejected within the burst", i+1)
+		}
+	}
+
+	res, err := limiter.Allow(ctx, "key", limit)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if res.Allowed {
+		t.Fatal("Allow() past the burst was allowed")
+	}
+	if res.RetryAfter <= 999*time.Second || res.RetryAfter > 1000*time.Second {
+		t.Errorf("RetryAfter = %v, want about 1000s", res.RetryAfter)
+	}
+
+	// Another key has its own bucket
+	res, err = limiter.Allow(ctx, "other", limit)
+	if err != nil {
+		t.Fatalf("Allow() error = %v", err)
+	}
+	if !res.Allowed {
+		t.Error("Allow() of another key was rejected")
+	}
+}
+
+func TestMemoryLimiterRefill(t *testing.T) {
+	ctx := context.Background()
+	limiter := NewMemoryLimiter().(*memoryLimiter)
+	limit := Limit{Rate: 1, Burst: 5}
+
+	for range limit.Burst {
+		if _, err := limiter.Allow(ctx, "key", limit); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+	}
+
+	// Two seconds ago the bucket was empty, so it holds two tokens now
+	limiter.buckets["key"].updated = time.Now().Add(-2 * time.Second)
+
+	var allowed int
+	for range limit.Burst {
+		res, err := limiter.Allow(ctx, "key", limit)
+		if err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		if res.Allowed {
+			allowed++
+		}
+	}
+	if allowed != 2 {
+		t.Errorf("allowed %d votes after refilling, want 2", allowed)
+	}
+}
+
+func TestMemoryLimiterRefund(t *testing.T) {
+	ctx := context.Background()
+	limit := Limit{Rate: 0.001, Burst: 3}
+
+	allowed := func(limiter Limiter) int {
+		var n int
+		for range limit.Burst + 2 {
+			res, err := limiter.Allow(ctx, "key", limit)
+			if err != nil {
+				t.Fatalf("Allow() error = %v", err)
+			}
+			if res.Allowed {
+				n++
+			}
+		}
+		return n
+	}
+
+	t.Run("returns a taken token", func(t *testing.T) {
+		limiter := NewMemoryLimiter()
+		if got := allowed(limiter); got != limit.Burst {
+			t.Fatalf("allowed %d, want %d", got, limit.Burst)
+		}
+
+		if err := limiter.Refund(ctx, "key", limit); err != nil {
+			t.Fatalf("Refund() error = %v", err)
+		}
+		if got := allowed(limiter); got != 1 {
+			t.Errorf("allowed %d after a refund, want 1", got)
+		}
+	})
+
+	t.Run("capped at the burst", func(t *testing.T) {
+		limiter := NewMemoryLimiter()
+		if _, err := limiter.Allow(ctx, "key", limit); err != nil {
+			t.Fatalf("Allow() error = %v", err)
+		}
+		for range 5 {
+			if err := limiter.Refund(ctx, "key", limit); err != nil {
+				t.Fatalf("Refund() error = %v", err)
+			}
+		}
+
+		if got := allowed(limiter); got != limit.Burst {
+			t.Errorf("allowed %d after refunds, want %d", got, limit.Burst)
+		}
+	})
+
+	t.Run("unknown key", func(t *testing.T) {
+		limiter := NewMemoryLimiter().(*memoryLimiter)
+		if err := limiter.Refund(ctx, "key", limit); err != nil {
+			t.Fatalf("Refund() error = %v", err)
+		}
+		if len(limiter.buckets) != 0 {
+			t.Errorf("Refund() created %d buckets, want none", len(limiter.buckets))
+		}
+	})
+}
@@ -0,0 +1,117 @@
+package ratelimit
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// idleBucketAge is how long a bucket goes unused before it is deleted
+// It must be longer than any bucket takes to refill
+const idleBucketAge = time.Hour
+
+// postgresLimiter keeps the buckets in PostgreSQL so every replica shares them
+type postgresLimiter struct {
+	db *sqlx.DB
+}
+
+func NewPostgresLimiter(db *sqlx.DB) *postgresLimiter {
+	return &postgresLimiter{db: db}
+}
+
+// Start deletes the idle buckets on every idleBucketAge until ctx is done
+func (p *postgresLimiter) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(idleBucketAge)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("stopping rate limit pruner")
+				return
+			case <-ticker.C:
+				_, err := p.db.ExecContext(
+					ctx,
+					"DELETE FROM rate_limits WHERE updated < $1",
+					time.Now().Add(-idleBucketAge),
+				)
+				if err != nil {
+					slog.Error("failed to prune rate limits", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+func (p *postgresLimiter) Allow(ctx context.Context, key string, limit Limit) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	// The row lock taken by the upsert makes the refill and the take atomic
+	// The update is skipped when the refilled bucket has no token, so nothing is returned
+	var query = `
+		INSERT INTO rate_limits (key, tokens, updated)
+		VALUES ($1, $2 - 1, clock_timestamp())
+		ON CONFLICT (key) DO UPDATE SET
+			tokens = LEAST(
+				$2,
+				rate_limits.tokens + EXTRACT(EPOCH FROM clock_timestamp() - rate_limits.updated) * $3
+			) - 1,
+			updated = clock_timestamp()
+		WHERE LEAST(
+			$2,
+			rate_limits.tokens + EXTRACT(EPOCH FROM clock_timestamp() - rate_limits.updated) * $3
+		) >= 1
+		RETURNING tokens
+	`
+
+	var tokens float64
+	err := p.db.GetContext(ctx, &tokens, query, key, limit.Burst, limit.Rate)
+	if err == nil {
+		return Result{Allowed: true}, nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		return Result{}, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+
+	var bucket struct {
+		Tokens  float64   `db:"tokens"`
+		Updated time.Time `db:"updated"`
+		Now     time.Time `db:"now"`
+	}
+	err = p.db.GetContext(
+		ctx,
+		&bucket,
+		"SELECT tokens, updated, clock_timestamp() AS now FROM rate_limits WHERE key = $1",
+		key,
+	)
+	if err != nil {
+		return Result{}, fmt.Errorf("failed to get rate limit bucket: %w", err)
+	}
+
+	tokens = refill(bucket.Tokens, bucket.Updated, bucket.Now, limit)
+	return Result{RetryAfter: retryAfter(tokens, limit)}, nil
+}
+
+func (p *postgresLimiter) Refund(ctx context.Context, key string, limit Limit) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	_, err := p.db.ExecContext(
+		ctx,
+		"UPDATE rate_limits SET tokens = LEAST($2, tokens + 1) WHERE key = $1",
+		key,
+		limit.Burst,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to refund rate limit token: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	BackendMemory   = "memory"
+	BackendPostgres = "postgres"
+)
+
+// Limit is a token bucket that holds up to Burst tokens and gains Rate tokens per second
+type Limit struct {
+	Rate  float64
+	Burst int
+}
+
+// Enabled reports whether the limit should be enforced, a zero rate disables it
+func (l Limit) Enabled() bool {
+	return l.Rate > 0 && l.Burst > 0
+}
+
+// Result is the outcome of taking a token from a bucket
+type Result struct {
+	Allowed bool
+	// RetryAfter is how long until the bucket has a token again, zero when allowed
+	RetryAfter time.Duration
+}
+
+// Limiter takes a token from the bucket identified by key
+// Buckets are created full the first time a key is seen
+type Limiter interface {
+	Allow(ctx context.Context, key string, limit Limit) (Result, error)
+	// Refund returns a token taken by Allow, up to the burst of the bucket
+	Refund(ctx context.Context, key string, limit Limit) error
+}
+
+// retryAfter returns how long a bucket with the given tokens takes to have one token
+func retryAfter(tokens float64, limit Limit) time.Duration {
+	missing := max(1-tokens, 0)
+	seconds := math.Ceil(missing / limit.Rate * 1000)
+	return time.Duration(seconds) * time.Millisecond
+}
+
+// refill returns the tokens of a bucket that had tokens at updated
+func refill(tokens float64, updated, now time.Time, limit Limit) float64 {
+	elapsed := now.Sub(updated).Seconds()
+	return math.Min(float64(limit.Burst), tokens+elapsed*limit.Rate)
+}
+
+// New returns the limiter of the given backend
+// The PostgreSQL backend also starts deleting idle buckets until ctx is done
+func New(ctx context.Context, backend string, db *sqlx.DB) (Limiter, error) {
+	switch backend {
+	case BackendMemory:
+		return NewMemoryLimiter(), nil
+	case BackendPostgres:
+		limiter := NewPostgresLimiter(db)
+		limiter.Start(ctx)
+		return limiter, nil
+	default:
+		return nil, fmt.Errorf("invalid rate limit backend %q", backend)
+	}
+}
@@ -0,0 +1,50 @@
+package signer
+
+import (
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+)
+
+// Signer signs payloads with an Ed25519 key, so anyone holding the public key
+// can check a signature without asking the server
+type Signer struct {
+	private ed25519.PrivateKey
+	public  ed25519.PublicKey
+}
+
+// New creates a signer from a base64 encoded 32 byte seed
+func New(seed string) (*Signer, error) {
+	raw, err := base64.StdEncoding.DecodeString(seed)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key: %w", err)
+	}
+	if len(raw) != ed25519.SeedSize {
+		return nil, fmt.Errorf("signing key must have %d bytes", ed25519.SeedSize)
+	}
+
+	private := ed25519.NewKeyFromSeed(raw)
+	return &Signer{
+		private: private,
+		public:  private.Public().(ed25519.PublicKey),
+	}, nil
+}
+
+// Sign returns the base64url encoded signature of payload
+func (s *Signer) Sign(payload []byte) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(s.private, payload))
+}
+
+// Verify reports whether signature is a valid signature of payload
+func (s *Signer) Verify(payload []byte, signature string) bool {
+	decoded, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return false
+	}
+	return ed25519.Verify(s.public, payload, decoded)
+}
+
+// PublicKey returns the base64 encoded public key
+func (s *Signer) PublicKey() string {
+	return base64.StdEncoding.EncodeToString(s.public)
+}
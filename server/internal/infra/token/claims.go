@@ -7,16 +7,31 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// Roles a user can have, admins manage users, eliminations and the vote queues
+const (
+	RoleUser  = "user"
+	RoleAdmin = "admin"
+)
+
 type Claims struct {
 	UserID string `json:"user_id"`
 	Email  string `json:"email"`
+	// Channel is the client the user authenticated through
+	Channel string `json:"channel"`
+	// Verified tells whether the user identity was verified when the token was issued
+	Verified bool `json:"verified"`
+	// Role is the role of the user when the token was issued
+	Role string `json:"role"`
 	jwt.RegisteredClaims
 }
 
-func NewClaims(userId, email string, duration time.Duration) (*Claims, error) {
+func NewClaims(userId, email, channel, role string, verified bool, duration time.Duration) (*Claims, error) {
 	claims := &Claims{
-		UserID: userId,
-		Email:  email,
+		UserID:   userId,
+		Email:    email,
+		Channel:  channel,
+		Verified: verified,
+		Role:     role,
 		RegisteredClaims: jwt.RegisteredClaims{
 			Subject:   email,
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
@@ -27,6 +42,11 @@ func NewClaims(userId, email string, duration time.Duration) (*Claims, error) {
 	return claims, nil
 }
 
+// IsAdmin tells whether the token was issued to an admin
+func (c *Claims) IsAdmin() bool {
+	return c.Role == RoleAdmin
+}
+
 func (c *Claims) Valid() error {
 	if time.Now().After(c.ExpiresAt.Time) {
 		return errors.New("token has expired")
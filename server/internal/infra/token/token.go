@@ -9,12 +9,12 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func Generate(key, userId, email string, d time.Duration) (string, *Claims, error) {
+func Generate(key, userId, email, channel, role string, verified bool, d time.Duration) (string, *Claims, error) {
 	if len(key) != chacha20poly1305.KeySize {
 		return "", nil, fmt.Errorf("invalid secret key")
 	}
 
-	claims, err := NewClaims(userId, email, d)
+	claims, err := NewClaims(userId, email, channel, role, verified, d)
 	if err != nil {
 		return "", nil, fmt.Errorf("failed to create claims: %w", err)
 	}
@@ -10,6 +10,7 @@ type Metric struct {
 	votingLatency    *prometheus.HistogramVec
 	participantVotes *prometheus.CounterVec
 	votingErrors     *prometheus.CounterVec
+	rateLimited      *prometheus.CounterVec
 }
 
 func NewMetric() *Metric {
@@ -54,6 +55,15 @@ func NewMetric() *Metric {
 			},
 			[]string{"error_type"},
 		),
+
+		// Votes rejected by the rate limiter
+		rateLimited: prometheus.NewCounterVec(
+			prometheus.CounterOpts{
+				Name: "bbb_votes_rate_limited_total",
+				Help: "Total votes rejected by the rate limiter",
+			},
+			[]string{"scope"},
+		),
 	}
 
 	registry.MustRegister(
@@ -61,6 +71,7 @@ func NewMetric() *Metric {
 		m.votingLatency,
 		m.participantVotes,
 		m.votingErrors,
+		m.rateLimited,
 	)
 
 	return m
@@ -71,6 +82,10 @@ func (m *Metric) RecordVote(participante, origem string) {
 	m.participantVotes.WithLabelValues(participante, origem).Inc()
 }
 
+func (m *Metric) RecordRateLimited(scope string) {
+	m.rateLimited.WithLabelValues(scope).Inc()
+}
+
 func (m *Metric) RecordError(tipoErro string) {
 	m.votingErrors.WithLabelValues(tipoErro).Inc()
 }
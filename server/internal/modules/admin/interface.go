@@ -0,0 +1,14 @@
+package admin
+
+import (
+	"context"
+
+	"github.com/bernardinorafael/globo-challenge/internal/queue"
+)
+
+type Service interface {
+	GetQueues(ctx context.Context) ([]queue.QueueInfo, error)
+	GetDeadLetters(ctx context.Context, queueName string, limit int) ([]queue.DeadLetter, error)
+	RequeueDeadLetters(ctx context.Context, queueName string, limit int) (int, error)
+	PurgeDeadLetters(ctx context.Context, queueName string) (int, error)
+}
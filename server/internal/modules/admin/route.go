@@ -0,0 +1,125 @@
+package admin
+
+import (
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/bernardinorafael/globo-challenge/internal/infra/http/middleware"
+	"github.com/bernardinorafael/globo-challenge/internal/util"
+	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+	"github.com/go-chi/chi"
+)
+
+var (
+	instance *controller
+	Once     sync.Once
+)
+
+type controller struct {
+	adminService Service
+	secretKey    string
+}
+
+func NewController(adminService Service, secretKey string) *controller {
+	Once.Do(func() {
+		instance = &controller{
+			adminService: adminService,
+			secretKey:    secretKey,
+		}
+	})
+	return instance
+}
+
+func (c controller) RegisterRoutes(r *chi.Mux) {
+	m := middleware.NewWithAuth(c.secretKey)
+
+	r.Route("/api/v1/admin/queues", func(r chi.Router) {
+		r.Use(m.WithAuth)
+		r.Use(m.WithAdmin)
+
+		r.Get("/", c.handleGetQueues)
+		r.Get("/{queueName}/dead-letters", c.handleGetDeadLetters)
+		r.Post("/{queueName}/dead-letters/requeue", c.handleRequeueDeadLetters)
+		r.Delete("/{queueName}/dead-letters", c.handlePurgeDeadLetters)
+	})
+}
+
+func (c controller) handleGetQueues(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	queues, err := c.adminService.GetQueues(ctx)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, queues)
+}
+
+func (c controller) handleGetDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, err := readLimit(r)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	letters, err := c.adminService.GetDeadLetters(ctx, chi.URLParam(r, "queueName"), limit)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, letters)
+}
+
+func (c controller) handleRequeueDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	limit, err := readLimit(r)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	queueName := chi.URLParam(r, "queueName")
+
+	count, err := c.adminService.RequeueDeadLetters(ctx, queueName, limit)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, DeadLettersResult{Queue: queueName, Affected: count})
+}
+
+func (c controller) handlePurgeDeadLetters(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	queueName := chi.URLParam(r, "queueName")
+
+	count, err := c.adminService.PurgeDeadLetters(ctx, queueName)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, DeadLettersResult{Queue: queueName, Affected: count})
+}
+
+// readLimit reads the optional "limit" query parameter
+func readLimit(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("limit")
+	if value == "" {
+		return 0, nil
+	}
+
+	limit, err := strconv.Atoi(value)
+	if err != nil || limit < 0 {
+		return 0, errs.NewUnprocessableEntityError("limit must be a positive number", err)
+	}
+
+	return limit, nil
+}
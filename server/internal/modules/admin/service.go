@@ -0,0 +1,91 @@
+package admin
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/bernardinorafael/globo-challenge/internal/queue"
+	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+)
+
+const (
+	defaultDeadLettersLimit = 50
+	maxDeadLettersLimit     = 500
+)
+
+type service struct {
+	ctx   context.Context
+	queue *queue.Queue
+}
+
+func NewService(ctx context.Context, queue *queue.Queue) Service {
+	return &service{
+		ctx:   ctx,
+		queue: queue,
+	}
+}
+
+func (s *service) GetQueues(ctx context.Context) ([]queue.QueueInfo, error) {
+	var infos = []queue.QueueInfo{}
+	for _, name := range queue.Queues {
+		res, err := s.queue.Inspect(name)
+		if err != nil {
+			slog.Error("failed to inspect queue", "queue", name, "error", err)
+			return nil, errs.NewBadRequestError("failed to inspect queue", err)
+		}
+		infos = append(infos, res...)
+	}
+
+	return infos, nil
+}
+
+func (s *service) GetDeadLetters(ctx context.Context, queueName string, limit int) ([]queue.DeadLetter, error) {
+	if !queue.IsWorkQueue(queueName) {
+		return nil, errs.NewNotFoundError("queue not found", nil)
+	}
+
+	letters, err := s.queue.PeekDeadLetters(queueName, normalizeLimit(limit))
+	if err != nil {
+		slog.Error("failed to get dead letters", "queue", queueName, "error", err)
+		return nil, errs.NewBadRequestError("failed to get dead letters", err)
+	}
+
+	return letters, nil
+}
+
+func (s *service) RequeueDeadLetters(ctx context.Context, queueName string, limit int) (int, error) {
+	if !queue.IsWorkQueue(queueName) {
+		return 0, errs.NewNotFoundError("queue not found", nil)
+	}
+
+	count, err := s.queue.RequeueDeadLetters(ctx, queueName, normalizeLimit(limit))
+	if err != nil {
+		slog.Error("failed to requeue dead letters", "queue", queueName, "requeued", count, "error", err)
+		return count, errs.NewBadRequestError("failed to requeue dead letters", err)
+	}
+	slog.Info("dead letters requeued", "queue", queueName, "requeued", count)
+
+	return count, nil
+}
+
+func (s *service) PurgeDeadLetters(ctx context.Context, queueName string) (int, error) {
+	if !queue.IsWorkQueue(queueName) {
+		return 0, errs.NewNotFoundError("queue not found", nil)
+	}
+
+	count, err := s.queue.PurgeDeadLetters(queueName)
+	if err != nil {
+		slog.Error("failed to purge dead letters", "queue", queueName, "error", err)
+		return 0, errs.NewBadRequestError("failed to purge dead letters", err)
+	}
+	slog.Info("dead letters purged", "queue", queueName, "purged", count)
+
+	return count, nil
+}
+
+func normalizeLimit(limit int) int {
+	if limit <= 0 {
+		return defaultDeadLettersLimit
+	}
+	return min(limit, maxDeadLettersLimit)
+}
@@ -0,0 +1,6 @@
+package admin
+
+type DeadLettersResult struct {
+	Queue    string `json:"queue"`
+	Affected int    `json:"affected"`
+}
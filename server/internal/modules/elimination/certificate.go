@@ -0,0 +1,120 @@
+package elimination
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/pkg/merkle"
+)
+
+// CertificateBody is the signed statement of the result of a finished elimination
+// The Merkle root covers the IDs of every counted vote, sorted, so a voter can
+// prove their receipt is in the certified set, and the ledger head anchors the
+// vote ledger outside the database so it cannot be rewritten unnoticed
+type CertificateBody struct {
+	EliminationID   string           `json:"elimination_id"`
+	Type            PollType         `json:"type"`
+	VoteMode        VoteMode         `json:"vote_mode"`
+	EliminatedCount int              `json:"eliminated_count"`
+	StartDate       time.Time        `json:"start_date"`
+	EndDate         time.Time        `json:"end_date"`
+	ClosedAt        time.Time        `json:"closed_at"`
+	TotalVotes      int              `json:"total_votes"`
+	Counts          []CertifiedCount `json:"counts"`
+	MerkleRoot      string           `json:"merkle_root"`
+	LedgerEntries   int64            `json:"ledger_entries"`
+	LedgerHead      string           `json:"ledger_head"`
+	Issued          time.Time        `json:"issued"`
+}
+
+// CertifiedCount is the final count of a participant in a certificate
+type CertifiedCount struct {
+	ParticipantID string `json:"participant_id"`
+	Name          string `json:"name"`
+	Votes         int    `json:"votes"`
+}
+
+// Certificate is a certificate body with its signature
+// The signature covers the exact bytes of Body, which are served unchanged
+type Certificate struct {
+	EliminationID string          `json:"-" db:"elimination_id"`
+	Body          json.RawMessage `json:"certificate" db:"body"`
+	Signature     string          `json:"signature" db:"signature"`
+	PublicKey     string          `json:"public_key" db:"-"`
+	Created       time.Time       `json:"-" db:"created"`
+}
+
+// CountedVote is a vote counted in the result of an elimination
+type CountedVote struct {
+	ID            string `db:"id"`
+	ParticipantID string `db:"participant_id"`
+}
+
+// MerkleProof proves that a vote is in the certified set of its elimination
+// Hashing the leaf with each step of the path, in order, yields the root
+type MerkleProof struct {
+	VoteID        string        `json:"vote_id"`
+	EliminationID string        `json:"elimination_id"`
+	Leaf          string        `json:"leaf"`
+	Index         int           `json:"index"`
+	Path          []merkle.Step `json:"path"`
+	Root          string        `json:"root"`
+}
+
+// buildCertificate returns the certificate body of an elimination closed with
+// the given counted votes and their tree, names are taken from the frozen snapshot
+// It fails when the votes do not add up to the snapshot, which happens when a
+// vote is stored between reading both, so the certificate is retried
+func buildCertificate(elimination Entity, snapshot []ParticipantResult, votes []CountedVote, tree MerkleTree) (*CertificateBody, error) {
+	if elimination.ClosedAt == nil {
+		return nil, fmt.Errorf("elimination %s has no close barrier", elimination.ID)
+	}
+
+	var counts = make(map[string]int, len(snapshot))
+	for _, v := range votes {
+		counts[v.ParticipantID]++
+	}
+
+	var total int
+	body := CertificateBody{
+		EliminationID:   elimination.ID,
+		Type:            elimination.Type,
+		VoteMode:        elimination.VoteMode,
+		EliminatedCount: max(elimination.EliminatedCount, 1),
+		StartDate:       elimination.StartDate.UTC(),
+		EndDate:         elimination.EndDate.UTC(),
+		ClosedAt:        elimination.ClosedAt.UTC(),
+		TotalVotes:      len(votes),
+		Counts:          make([]CertifiedCount, 0, len(snapshot)),
+		MerkleRoot:      tree.Root(),
+		Issued:          time.Now().UTC(),
+	}
+	for _, r := range snapshot {
+		if counts[r.ID] != r.Count {
+			return nil, fmt.Errorf("votes of participant %s do not match the snapshot", r.ID)
+		}
+		total += r.Count
+		body.Counts = append(body.Counts, CertifiedCount{
+			ParticipantID: r.ID,
+			Name:          r.Name,
+			Votes:         counts[r.ID],
+		})
+	}
+	if total != len(votes) {
+		return nil, fmt.Errorf("counted votes do not match the snapshot")
+	}
+
+	return &body, nil
+}
+
+// voteIDs returns the sorted IDs of votes, the order of the Merkle tree leaves
+func voteIDs(votes []CountedVote) []string {
+	var ids = make([]string, 0, len(votes))
+	for _, v := range votes {
+		ids = append(ids, v.ID)
+	}
+	slices.Sort(ids)
+	return ids
+}
@@ -3,59 +3,227 @@ package elimination
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
+	"time"
 
+	"github.com/bernardinorafael/globo-challenge/internal/infra/client"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/signer"
 	"github.com/bernardinorafael/globo-challenge/internal/metric"
 	"github.com/bernardinorafael/globo-challenge/internal/queue"
 	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+	amqp "github.com/rabbitmq/amqp091-go"
 )
 
 type consumer struct {
 	queue           *queue.Queue
 	eliminationRepo Repository
 	metrics         *metric.Metric
+	writer          *voteWriter
+	tally           *Tally
+	validator       *voteValidator
 }
 
-func NewConsumer(queue *queue.Queue, metrics *metric.Metric, eliminationRepo Repository) *consumer {
+func NewConsumer(
+	queue *queue.Queue,
+	metrics *metric.Metric,
+	eliminationRepo Repository,
+	tally *Tally,
+	signer *signer.Signer,
+	batchSize int,
+	flushInterval time.Duration,
+	unsignedGrace time.Duration,
+) *consumer {
 	return &consumer{
 		queue:           queue,
 		eliminationRepo: eliminationRepo,
 		metrics:         metrics,
+		writer:          newVoteWriter(queue, metrics, eliminationRepo, tally, batchSize, flushInterval),
+		tally:           tally,
+		validator:       newVoteValidator(eliminationRepo, signer, time.Now().Add(unsignedGrace)),
 	}
 }
 
 func (c *consumer) Consume(ctx context.Context) error {
-	messages, err := c.queue.Consume(queue.VotesQueueName)
+	// Tallies held by a consumer that stopped without flushing are lost, so the
+	// counters of the active eliminations are rebuilt before consuming
+	if err := c.rebuildCounters(ctx); err != nil {
+		return errs.NewBadRequestError("failed to rebuild vote counters", err)
+	}
+
+	// The prefetch must hold more than a full batch, otherwise the broker
+	// stops delivering before the batch reaches its size threshold
+	messages, err := c.queue.Consume(queue.VotesQueueName, c.writer.size*2)
 	if err != nil {
 		c.metrics.RecordError("queue_consume_error")
 		return errs.NewBadRequestError("failed to consumed queue", err)
 	}
 
 	go func() {
+		ticker := time.NewTicker(c.writer.interval / 2)
+		defer ticker.Stop()
+
 		for {
 			select {
 			case <-ctx.Done():
 				slog.Info("stopping votes consumer")
+				c.writer.Flush(context.WithoutCancel(ctx))
+				if err := c.tally.Flush(context.WithoutCancel(ctx)); err != nil {
+					slog.Error("failed to flush vote tally", "error", err)
+				}
 				return
-			case msg := <-messages:
-				var timer = c.metrics.ObserveVotingLatency("vote_processing")
+			case <-ticker.C:
+				c.writer.FlushIfDue(ctx)
+			case msg, ok := <-messages:
+				if !ok {
+					slog.Warn("votes queue channel closed, stopping votes consumer")
+					return
+				}
+
+				if msg.Type == queue.BarrierMessageType {
+					c.handleBarrier(ctx, msg)
+					continue
+				}
 
 				var v Vote
-				_ = json.Unmarshal(msg.Body, &v)
+				if err := json.Unmarshal(msg.Body, &v); err != nil {
+					// A malformed message will never succeed, so it skips the retries
+					c.metrics.RecordError("vote_decode_error")
+					slog.Error("failed to decode vote", "error", err)
+					if err := c.queue.DeadLetter(ctx, queue.VotesQueueName, msg, err); err != nil {
+						c.metrics.RecordError("queue_dead_letter_error")
+						slog.Error("failed to dead-letter vote", "error", err)
+					}
+					continue
+				}
+
+				// Votes published before origins existed all came from the web client
+				if v.Origin == "" {
+					v.Origin = string(client.DefaultChannel)
+				}
+				// Same for pools, every vote before dual-pool eliminations was a fan vote
+				if v.Pool == "" {
+					v.Pool = PoolFan
+				}
+
+				err := c.validator.Check(ctx, &v)
+				if err != nil {
+					var appErr errs.ApplicationError
+					if !errors.As(err, &appErr) {
+						c.metrics.RecordError("vote_validation_error")
+						slog.Error("failed to validate vote", "vote_id", v.ID, "error", err)
+						if err := c.queue.Retry(ctx, queue.VotesQueueName, msg, err); err != nil {
+							c.metrics.RecordError("queue_retry_error")
+							slog.Error("failed to retry vote", "error", err)
+						}
+						continue
+					}
 
-				if err := c.eliminationRepo.InsertVote(ctx, v); err != nil {
-					c.metrics.RecordError("database_insert_error")
-					slog.Error("failed to insert vote", "error", err)
-					timer.ObserveDuration()
+					// A rejected vote will never be valid, so it skips the retries
+					c.metrics.RecordError("vote_rejected")
+					slog.Warn("vote rejected", "vote_id", v.ID, "code", appErr.Code, "reason", appErr.Msg)
+					c.recordRejection(ctx, v, appErr)
+					if err := c.queue.DeadLetter(ctx, queue.VotesQueueName, msg, err); err != nil {
+						c.metrics.RecordError("queue_dead_letter_error")
+						slog.Error("failed to dead-letter vote", "error", err)
+					}
 					continue
 				}
 
-				c.metrics.RecordVote(v.ParticipantID, "processed")
-				timer.ObserveDuration()
-				slog.Info("vote inserted", "vote_id", v.ID)
+				c.writer.Add(ctx, v, msg)
 			}
 		}
 	}()
 
 	return nil
 }
+
+// handleBarrier flushes every vote received before the close barrier of an
+// elimination and its tally, and marks the elimination as drained
+// A failed tally flush does not hold the barrier, the counters of the
+// elimination are rebuilt when it is finalized
+func (c *consumer) handleBarrier(ctx context.Context, msg amqp.Delivery) {
+	var b Barrier
+	if err := json.Unmarshal(msg.Body, &b); err != nil {
+		c.metrics.RecordError("barrier_decode_error")
+		slog.Error("failed to decode close barrier", "error", err)
+		if err := c.queue.DeadLetter(ctx, queue.VotesQueueName, msg, err); err != nil {
+			c.metrics.RecordError("queue_dead_letter_error")
+			slog.Error("failed to dead-letter close barrier", "error", err)
+		}
+		return
+	}
+
+	c.writer.Flush(ctx)
+
+	if err := c.tally.Flush(ctx); err != nil {
+		c.metrics.RecordError("tally_flush_error")
+		slog.Error("failed to flush vote tally", "elimination_id", b.EliminationID, "error", err)
+	}
+
+	if err := c.eliminationRepo.MarkDrained(ctx, b.EliminationID); err != nil {
+		c.metrics.RecordError("database_update_error")
+		slog.Error("failed to mark elimination as drained", "elimination_id", b.EliminationID, "error", err)
+		if err := c.queue.Retry(ctx, queue.VotesQueueName, msg, err); err != nil {
+			c.metrics.RecordError("queue_retry_error")
+			slog.Error("failed to retry close barrier", "error", err)
+		}
+		return
+	}
+
+	if err := msg.Ack(false); err != nil {
+		c.metrics.RecordError("queue_ack_error")
+		slog.Error("failed to ack close barrier", "error", err)
+	}
+	slog.Info("close barrier reached", "elimination_id", b.EliminationID)
+}
+
+// rebuildCounters rebuilds the vote counters of the open and closing eliminations
+func (c *consumer) rebuildCounters(ctx context.Context) error {
+	eliminations, err := c.eliminationRepo.GetAll(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, e := range eliminations {
+		if e.Status != StatusOpen && e.Status != StatusClosing {
+			continue
+		}
+
+		rebuild, err := c.eliminationRepo.RebuildCounters(ctx, e.ID)
+		if err != nil {
+			return err
+		}
+		if rebuild.Before != rebuild.After {
+			slog.Warn(
+				"vote counters had drifted",
+				"elimination_id", e.ID,
+				"counted", rebuild.Before,
+				"stored", rebuild.After,
+			)
+		}
+	}
+
+	return nil
+}
+
+// recordRejection stores why a vote was rejected so its owner can look it up
+// Votes without an ID cannot be looked up, so they are not recorded
+func (c *consumer) recordRejection(ctx context.Context, v Vote, appErr errs.ApplicationError) {
+	if v.ID == "" {
+		return
+	}
+
+	err := c.eliminationRepo.InsertRejection(ctx, Rejection{
+		VoteID:        v.ID,
+		UserID:        v.UserID,
+		EliminationID: v.EliminationID,
+		Code:          string(appErr.Code),
+		Reason:        appErr.Msg,
+		Created:       time.Now(),
+	})
+	if err != nil {
+		c.metrics.RecordError("database_insert_error")
+		slog.Error("failed to record vote rejection", "vote_id", v.ID, "error", err)
+	}
+}
@@ -0,0 +1,314 @@
+package elimination
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// counterBucket is the time span a vote counter covers
+const counterBucket = time.Minute
+
+// CounterRebuild is the result of recomputing the counters of an elimination
+type CounterRebuild struct {
+	EliminationID string `json:"elimination_id"`
+	// Before and After are the vote totals of the counters, they differ when
+	// the counters had drifted from the stored votes
+	Before int `json:"before"`
+	After  int `json:"after"`
+	// Epoch is the counter epoch started by the rebuild
+	Epoch int64 `json:"epoch"`
+}
+
+// Insertion is the outcome of storing votes
+type Insertion struct {
+	// Votes are the votes actually inserted, without the redelivered ones
+	Votes []Vote
+	// Rejected are the votes refused by a constraint of the votes table
+	Rejected []Rejection
+	// Epochs holds the counter epoch of each elimination the votes were stored in
+	Epochs map[string]int64
+}
+
+// CounterDelta is a number of votes to add to a vote counter
+// A delta is only applied while its epoch is the current epoch of its elimination
+type CounterDelta struct {
+	EliminationID string    `db:"elimination_id"`
+	ParticipantID string    `db:"participant_id"`
+	Origin        string    `db:"origin"`
+	Pool          Pool      `db:"pool"`
+	Bucket        time.Time `db:"bucket"`
+	Epoch         int64     `db:"-"`
+	Count         int64     `db:"count"`
+	ReachedAt     time.Time `db:"reached_at"`
+}
+
+// counterEpochs returns the current counter epoch of each elimination
+// It must run under the ledger locks of the eliminations, so a rebuild cannot
+// start a new epoch before tx commits
+func counterEpochs(ctx context.Context, tx *sqlx.Tx, eliminationIds []string) (map[string]int64, error) {
+	var rows []struct {
+		EliminationID string `db:"elimination_id"`
+		Epoch         int64  `db:"epoch"`
+	}
+
+	err := tx.SelectContext(
+		ctx,
+		&rows,
+		"SELECT elimination_id, epoch FROM vote_counter_epochs WHERE elimination_id = ANY($1)",
+		pq.Array(eliminationIds),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counter epochs: %w", err)
+	}
+
+	// An elimination that was never rebuilt is in its first epoch
+	var epochs = make(map[string]int64, len(eliminationIds))
+	for _, id := range eliminationIds {
+		epochs[id] = 0
+	}
+	for _, row := range rows {
+		epochs[row.EliminationID] = row.Epoch
+	}
+
+	return epochs, nil
+}
+
+// recordInserted appends the votes just stored in tx to the ledger and records
+// their voters, returning the insertion to be tallied
+// written are all the votes the insert was given, the ones it refused are
+// recorded as rejected
+func recordInserted(ctx context.Context, tx *sqlx.Tx, written, votes []Vote) (*Insertion, error) {
+	rejected, err := rejectConflicts(ctx, tx, written, votes)
+	if err != nil {
+		return nil, err
+	}
+	if len(votes) == 0 {
+		return &Insertion{Rejected: rejected}, nil
+	}
+
+	if err := appendLedger(ctx, tx, votes); err != nil {
+		return nil, err
+	}
+	if err := insertVoters(ctx, tx, votes); err != nil {
+		return nil, err
+	}
+
+	var eliminationIds []string
+	for _, v := range votes {
+		if !slices.Contains(eliminationIds, v.EliminationID) {
+			eliminationIds = append(eliminationIds, v.EliminationID)
+		}
+	}
+	epochs, err := counterEpochs(ctx, tx, eliminationIds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Insertion{Votes: votes, Rejected: rejected, Epochs: epochs}, nil
+}
+
+// rejectConflicts records as rejected the written votes that were neither
+// inserted nor stored before, which only happens to a second verified vote of a
+// user in an elimination, so its owner sees why it was not counted
+// Votes stored before are redeliveries and are not rejected
+func rejectConflicts(ctx context.Context, tx *sqlx.Tx, written, inserted []Vote) ([]Rejection, error) {
+	var skipped = make(map[string]Vote)
+	for _, v := range written {
+		skipped[v.ID] = v
+	}
+	for _, v := range inserted {
+		delete(skipped, v.ID)
+	}
+	if len(skipped) == 0 {
+		return nil, nil
+	}
+
+	var ids = make([]string, 0, len(skipped))
+	for id := range skipped {
+		ids = append(ids, id)
+	}
+
+	var stored []string
+	err := tx.SelectContext(ctx, &stored, "SELECT id FROM votes WHERE id = ANY($1)", pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get stored votes: %w", err)
+	}
+	for _, id := range stored {
+		delete(skipped, id)
+	}
+	if len(skipped) == 0 {
+		return nil, nil
+	}
+
+	var rejections = make([]Rejection, 0, len(skipped))
+	for _, v := range skipped {
+		rejections = append(rejections, Rejection{
+			VoteID:        v.ID,
+			UserID:        v.UserID,
+			EliminationID: v.EliminationID,
+			Code:          string(errs.ResourceConflict),
+			Reason:        "verified vote already cast in this elimination",
+			Created:       time.Now(),
+		})
+	}
+
+	if err := insertRejections(ctx, tx, rejections); err != nil {
+		return nil, err
+	}
+
+	return rejections, nil
+}
+
+// rejectDrained records as rejected the written votes of the eliminations that
+// already reached their close barrier, returning the votes that can be stored
+// The ledger and the rows of the eliminations stay locked until tx ends, so an
+// elimination cannot be drained while its votes are being stored, and every
+// vote stored before the drain is seen by the finalization
+// Votes stored before are redeliveries and are not rejected
+func rejectDrained(ctx context.Context, tx *sqlx.Tx, written []Vote) ([]Vote, []Rejection, error) {
+	var eliminationIds []string
+	for _, v := range written {
+		if !slices.Contains(eliminationIds, v.EliminationID) {
+			eliminationIds = append(eliminationIds, v.EliminationID)
+		}
+	}
+	// Same order as appendLedger, the ledger lock is always taken before the row
+	slices.Sort(eliminationIds)
+	for _, id := range eliminationIds {
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", ledgerLockClass, id)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to lock ledger: %w", err)
+		}
+	}
+
+	// Every row is locked, not only the drained ones, so MarkDrained waits for tx
+	var query = `
+		SELECT
+			id,
+			drained_at IS NOT NULL OR status = 'closed' AS "drained"
+		FROM eliminations
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR SHARE
+	`
+
+	var rows []struct {
+		ID      string `db:"id"`
+		Drained bool   `db:"drained"`
+	}
+	err := tx.SelectContext(ctx, &rows, query, pq.Array(eliminationIds))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to lock eliminations: %w", err)
+	}
+
+	var drained []string
+	for _, row := range rows {
+		if row.Drained {
+			drained = append(drained, row.ID)
+		}
+	}
+	if len(drained) == 0 {
+		return written, nil, nil
+	}
+
+	var late []string
+	var accepted = make([]Vote, 0, len(written))
+	for _, v := range written {
+		if slices.Contains(drained, v.EliminationID) {
+			late = append(late, v.ID)
+			continue
+		}
+		accepted = append(accepted, v)
+	}
+
+	var stored []string
+	err = tx.SelectContext(ctx, &stored, "SELECT id FROM votes WHERE id = ANY($1)", pq.Array(late))
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to get stored votes: %w", err)
+	}
+
+	var rejections []Rejection
+	for _, v := range written {
+		if !slices.Contains(late, v.ID) || slices.Contains(stored, v.ID) {
+			continue
+		}
+		rejections = append(rejections, Rejection{
+			VoteID:        v.ID,
+			UserID:        v.UserID,
+			EliminationID: v.EliminationID,
+			Code:          string(errs.InvalidState),
+			Reason:        "elimination was already closed when the vote was stored",
+			Created:       time.Now(),
+		})
+	}
+	if err := insertRejections(ctx, tx, rejections); err != nil {
+		return nil, nil, err
+	}
+
+	return accepted, rejections, nil
+}
+
+// insertRejections records rejected votes in tx, a vote already rejected is kept as is
+func insertRejections(ctx context.Context, tx *sqlx.Tx, rejections []Rejection) error {
+	if len(rejections) == 0 {
+		return nil
+	}
+
+	var query = `
+		INSERT INTO vote_rejections (
+			vote_id,
+			user_id,
+			elimination_id,
+			code,
+			reason,
+			created
+		) VALUES (
+			:vote_id,
+			:user_id,
+			:elimination_id,
+			:code,
+			:reason,
+			:created
+		)
+		ON CONFLICT (vote_id) DO NOTHING
+	`
+
+	_, err := tx.NamedExecContext(ctx, query, rejections)
+	if err != nil {
+		return fmt.Errorf("failed to insert vote rejections: %w", err)
+	}
+
+	return nil
+}
+
+// insertVoters records the voters of the votes just stored in tx
+func insertVoters(ctx context.Context, tx *sqlx.Tx, votes []Vote) error {
+	var voters = make(map[[2]string]bool)
+	for _, v := range votes {
+		voters[[2]string{v.EliminationID, v.UserID}] = true
+	}
+	var eliminationIds, userIds = make([]string, 0, len(voters)), make([]string, 0, len(voters))
+	for voter := range voters {
+		eliminationIds = append(eliminationIds, voter[0])
+		userIds = append(userIds, voter[1])
+	}
+
+	var query = `
+		INSERT INTO elimination_voters (elimination_id, user_id)
+		SELECT * FROM unnest($1::text[], $2::text[])
+		ON CONFLICT DO NOTHING
+	`
+
+	_, err := tx.ExecContext(ctx, query, pq.Array(eliminationIds), pq.Array(userIds))
+	if err != nil {
+		return fmt.Errorf("failed to insert voters: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,88 @@
+package elimination
+
+import (
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// defaultDashboardBucket and defaultDashboardTimezone are used when the
+	// dashboard query leaves them out
+	defaultDashboardBucket   = Bucket1h
+	defaultDashboardTimezone = "UTC"
+	// maxSeriesPoints caps the time series, a window too long for the bucket
+	// must be queried with a wider one
+	maxSeriesPoints = 5000
+)
+
+// Bucket is the width of a point of the dashboard time series
+type Bucket string
+
+const (
+	Bucket1m Bucket = "1m"
+	Bucket5m Bucket = "5m"
+	Bucket1h Bucket = "1h"
+)
+
+// Duration returns the width of the bucket, false when it is not a known bucket
+func (b Bucket) Duration() (time.Duration, bool) {
+	switch b {
+	case Bucket1m:
+		return time.Minute, true
+	case Bucket5m:
+		return 5 * time.Minute, true
+	case Bucket1h:
+		return time.Hour, true
+	default:
+		return 0, false
+	}
+}
+
+// SeriesPoint is the number of votes cast in a bucket starting at Start
+type SeriesPoint struct {
+	Start time.Time `json:"start" db:"start"`
+	Votes int       `json:"votes" db:"votes"`
+}
+
+// votingWindow returns the period an elimination has been taking votes in,
+// from its start date to its close, or to now while it is still open
+func votingWindow(elimination Entity, now time.Time) (time.Time, time.Time) {
+	end := elimination.EndDate
+	if elimination.ClosedAt != nil {
+		end = *elimination.ClosedAt
+	}
+	if now.Before(end) {
+		end = now
+	}
+	return elimination.StartDate, end
+}
+
+// votesPerHour returns the vote rate over a window, rounded to one decimal
+func votesPerHour(votes int, from, to time.Time) float64 {
+	hours := to.Sub(from).Hours()
+	if hours <= 0 {
+		return 0
+	}
+	return math.Round(float64(votes)/hours*10) / 10
+}
+
+// peakPoint returns the point with the most votes, the earliest one on a tie,
+// or nil when no point has votes
+func peakPoint(series []SeriesPoint) *SeriesPoint {
+	var peak *SeriesPoint
+	for i := range series {
+		if series[i].Votes > 0 && (peak == nil || series[i].Votes > peak.Votes) {
+			peak = &series[i]
+		}
+	}
+	return peak
+}
+
+// checkSeriesSize fails when the window holds more points than maxSeriesPoints
+func checkSeriesSize(from, to time.Time, width time.Duration) error {
+	if points := to.Sub(from) / width; points > maxSeriesPoints {
+		return fmt.Errorf("the voting window has %d buckets of %s, more than the %d allowed", points, width, maxSeriesPoints)
+	}
+	return nil
+}
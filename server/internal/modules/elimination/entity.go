@@ -1,71 +1,265 @@
 package elimination
 
 import (
+	"errors"
+	"fmt"
+	"slices"
 	"time"
 
 	"github.com/bernardinorafael/globo-challenge/internal/util"
 )
 
 const (
-	// TODO: Implement start and end date dynamically
-	eliminationDuration = time.Hour * 24 * 1 // 1 day
+	// defaultEliminationDuration is used when an elimination is created without an end date
+	defaultEliminationDuration = time.Hour * 24 * 1 // 1 day
+	minEliminationDuration     = time.Minute * 5
+	// startDateTolerance allows a start date slightly in the past to account for clock skew
+	startDateTolerance = time.Minute
 )
 
+// Settings holds the rules a new elimination is created with
+type Settings struct {
+	// A nil start date opens the elimination right away and a nil end date
+	// closes it after the default duration
+	StartDate        *time.Time
+	EndDate          *time.Time
+	Type             PollType
+	VoteMode         VoteMode
+	EliminatedCount  int
+	Participants     []string
+	TieBreakPolicy   TieBreakPolicy
+	TieBreakPriority []string
+	VotingScheme     VotingScheme
+	VerifiedWeight   int
+	FanWeight        int
+}
+
 // elimination is the internal representation of the elimination entity
 type elimination struct {
-	id        string
-	open      bool
-	startDate time.Time
-	endDate   time.Time
-	created   time.Time
-	updated   time.Time
+	id               string
+	open             bool
+	pollType         PollType
+	voteMode         VoteMode
+	eliminatedCount  int
+	status           Status
+	startDate        time.Time
+	endDate          time.Time
+	participants     []string
+	tieBreakPolicy   TieBreakPolicy
+	tieBreakPriority []string
+	tieBreakDecision *string
+	votingScheme     VotingScheme
+	verifiedWeight   int
+	fanWeight        int
+	closedAt         *time.Time
+	drainedAt        *time.Time
+	created          time.Time
+	updated          time.Time
 }
 
 // NewEliminationFromDatabase creates a new elimination entity from a database entity
 func NewEliminationFromDatabase(entity Entity) *elimination {
 	return &elimination{
-		id:        entity.ID,
-		open:      entity.Open,
-		startDate: entity.StartDate,
-		endDate:   entity.EndDate,
-		created:   entity.Created,
-		updated:   entity.Updated,
+		id:               entity.ID,
+		open:             entity.Open,
+		pollType:         entity.Type,
+		voteMode:         entity.VoteMode,
+		eliminatedCount:  entity.EliminatedCount,
+		status:           entity.Status,
+		startDate:        entity.StartDate,
+		endDate:          entity.EndDate,
+		tieBreakPolicy:   entity.TieBreakPolicy,
+		tieBreakPriority: entity.TieBreakPriority,
+		tieBreakDecision: entity.TieBreakDecision,
+		votingScheme:     entity.VotingScheme,
+		verifiedWeight:   entity.VerifiedWeight,
+		fanWeight:        entity.FanWeight,
+		closedAt:         entity.ClosedAt,
+		drainedAt:        entity.DrainedAt,
+		created:          entity.Created,
+		updated:          entity.Updated,
 	}
 }
 
 // NewElimination creates a new elimination entity
-func NewElimination() *elimination {
-	return &elimination{
-		id:        util.GenID("elim"),
-		open:      true,
-		startDate: time.Now(),
-		endDate:   time.Now().Add(eliminationDuration),
-		created:   time.Now(),
-		updated:   time.Now(),
+// An elimination starting in the future is created as scheduled
+func NewElimination(settings Settings) (*elimination, error) {
+	now := time.Now()
+
+	e := elimination{
+		id:               util.GenID("elim"),
+		startDate:        now,
+		pollType:         settings.Type,
+		voteMode:         settings.VoteMode,
+		eliminatedCount:  settings.EliminatedCount,
+		participants:     settings.Participants,
+		tieBreakPolicy:   settings.TieBreakPolicy,
+		tieBreakPriority: settings.TieBreakPriority,
+		votingScheme:     settings.VotingScheme,
+		verifiedWeight:   settings.VerifiedWeight,
+		fanWeight:        settings.FanWeight,
+		created:          now,
+		updated:          now,
+	}
+	if settings.StartDate != nil {
+		e.startDate = *settings.StartDate
+	}
+	if settings.EndDate != nil {
+		e.endDate = *settings.EndDate
+	} else {
+		e.endDate = e.startDate.Add(defaultEliminationDuration)
+	}
+	if e.pollType == "" {
+		e.pollType = PollElimination
+	}
+	if e.voteMode == "" {
+		e.voteMode = VoteToEliminate
+	}
+	if e.eliminatedCount == 0 {
+		e.eliminatedCount = 1
+	}
+	if e.tieBreakPolicy == "" {
+		e.tieBreakPolicy = TieBreakEarliestVote
+	}
+	if e.tieBreakPriority == nil {
+		e.tieBreakPriority = []string{}
+	}
+	if e.votingScheme == "" {
+		e.votingScheme = VotingSchemeSingle
+	}
+
+	if err := e.validate(now); err != nil {
+		return nil, err
+	}
+
+	if e.startDate.After(now) {
+		e.status = StatusScheduled
+	} else {
+		e.open = true
+		e.status = StatusOpen
+	}
+
+	return &e, nil
+}
+
+// validate validates the elimination type, vote mode, eliminated count, dates and tie-break rules
+func (e *elimination) validate(now time.Time) error {
+	if !e.pollType.IsValid() {
+		return fmt.Errorf("invalid poll type %q", e.pollType)
+	}
+	if !e.voteMode.IsValid() {
+		return fmt.Errorf("invalid vote mode %q", e.voteMode)
+	}
+	if e.voteMode == VoteToSave && e.pollType != PollElimination {
+		return errors.New("the save vote mode is only allowed in elimination polls")
+	}
+	if e.eliminatedCount < 1 {
+		return errors.New("eliminated count must be at least 1")
+	}
+	if e.eliminatedCount > 1 {
+		if e.pollType != PollElimination {
+			return errors.New("only elimination polls can eliminate more than one participant")
+		}
+		// A manual decision names a single participant
+		if e.tieBreakPolicy == TieBreakManual {
+			return errors.New("the manual tie-break policy only supports a single eliminated participant")
+		}
 	}
+	if e.eliminatedCount >= len(e.participants) {
+		return errors.New("an elimination must have more participants than it eliminates")
+	}
+	if e.startDate.Before(now.Add(-startDateTolerance)) {
+		return errors.New("start date cannot be in the past")
+	}
+	if !e.endDate.After(e.startDate) {
+		return errors.New("end date must be after start date")
+	}
+	if e.endDate.Sub(e.startDate) < minEliminationDuration {
+		return fmt.Errorf("elimination must last at least %s", minEliminationDuration)
+	}
+
+	if !e.tieBreakPolicy.IsValid() {
+		return fmt.Errorf("invalid tie-break policy %q", e.tieBreakPolicy)
+	}
+	if e.tieBreakPolicy == TieBreakPriority {
+		// Every participant must have a position, otherwise a tie could be left undecided
+		for _, participantId := range e.participants {
+			if !slices.Contains(e.tieBreakPriority, participantId) {
+				return errors.New("tie-break priority must list every participant")
+			}
+		}
+	} else if len(e.tieBreakPriority) > 0 {
+		return errors.New("tie-break priority is only allowed with the priority policy")
+	}
+
+	if !e.votingScheme.IsValid() {
+		return fmt.Errorf("invalid voting scheme %q", e.votingScheme)
+	}
+	if e.votingScheme == VotingSchemeDualPool {
+		if e.verifiedWeight <= 0 || e.fanWeight <= 0 || e.verifiedWeight+e.fanWeight != 100 {
+			return errors.New("verified and fan weights must be positive and add up to 100")
+		}
+	} else if e.verifiedWeight != 0 || e.fanWeight != 0 {
+		return errors.New("pool weights are only allowed with the dual_pool voting scheme")
+	}
+
+	return nil
 }
 
-// Finish closes the elimination
-func (e *elimination) Finish() {
-	e.open = false
+// DecideTie records the participant an admin chose in a tie
+func (e *elimination) DecideTie(participantId string) error {
+	if e.status == StatusClosed {
+		return errors.New("elimination already finished")
+	}
+	if e.tieBreakPolicy != TieBreakManual {
+		return errors.New("elimination does not use the manual tie-break policy")
+	}
+
+	e.tieBreakDecision = &participantId
 	e.updated = time.Now()
+	return nil
 }
 
 // Store returns the elimination entity in a format that can be stored in the database
 func (e *elimination) Store() Entity {
 	return Entity{
-		ID:        e.id,
-		Open:      e.open,
-		StartDate: e.startDate,
-		EndDate:   e.endDate,
-		Created:   e.created,
-		Updated:   e.updated,
+		ID:               e.id,
+		Open:             e.open,
+		Type:             e.pollType,
+		VoteMode:         e.voteMode,
+		EliminatedCount:  e.eliminatedCount,
+		Status:           e.status,
+		StartDate:        e.startDate,
+		EndDate:          e.endDate,
+		TieBreakPolicy:   e.tieBreakPolicy,
+		TieBreakPriority: e.tieBreakPriority,
+		TieBreakDecision: e.tieBreakDecision,
+		VotingScheme:     e.votingScheme,
+		VerifiedWeight:   e.verifiedWeight,
+		FanWeight:        e.fanWeight,
+		ClosedAt:         e.closedAt,
+		DrainedAt:        e.drainedAt,
+		Created:          e.created,
+		Updated:          e.updated,
 	}
 }
 
-func (e *elimination) ID() string           { return e.id }
-func (e *elimination) Open() bool           { return e.open }
-func (e *elimination) StartDate() time.Time { return e.startDate }
-func (e *elimination) EndDate() time.Time   { return e.endDate }
-func (e *elimination) Created() time.Time   { return e.created }
-func (e *elimination) Updated() time.Time   { return e.updated }
+func (e *elimination) ID() string                     { return e.id }
+func (e *elimination) Open() bool                     { return e.open }
+func (e *elimination) Type() PollType                 { return e.pollType }
+func (e *elimination) VoteMode() VoteMode             { return e.voteMode }
+func (e *elimination) EliminatedCount() int           { return e.eliminatedCount }
+func (e *elimination) Status() Status                 { return e.status }
+func (e *elimination) StartDate() time.Time           { return e.startDate }
+func (e *elimination) EndDate() time.Time             { return e.endDate }
+func (e *elimination) Participants() []string         { return e.participants }
+func (e *elimination) TieBreakPolicy() TieBreakPolicy { return e.tieBreakPolicy }
+func (e *elimination) TieBreakPriority() []string     { return e.tieBreakPriority }
+func (e *elimination) VotingScheme() VotingScheme     { return e.votingScheme }
+func (e *elimination) VerifiedWeight() int            { return e.verifiedWeight }
+func (e *elimination) FanWeight() int                 { return e.fanWeight }
+func (e *elimination) ClosedAt() *time.Time           { return e.closedAt }
+func (e *elimination) DrainedAt() *time.Time          { return e.drainedAt }
+func (e *elimination) TieBreakDecision() *string      { return e.tieBreakDecision }
+func (e *elimination) Created() time.Time             { return e.created }
+func (e *elimination) Updated() time.Time             { return e.updated }
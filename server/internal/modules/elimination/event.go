@@ -0,0 +1,70 @@
+package elimination
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/util"
+	"github.com/jmoiron/sqlx"
+)
+
+// EventType is the kind of change an audit event records
+type EventType string
+
+const (
+	// EventParticipantRemoved records a participant removed from an open
+	// elimination and the votes annulled with them
+	EventParticipantRemoved EventType = "participant_removed"
+)
+
+// Event is the audit record of a change made to an elimination after it was created
+// Events are only appended, in the same transaction as the change they record
+type Event struct {
+	ID            string    `json:"id" db:"id"`
+	EliminationID string    `json:"elimination_id" db:"elimination_id"`
+	Type          EventType `json:"type" db:"type"`
+	ParticipantID *string   `json:"participant_id" db:"participant_id"`
+	ActorID       string    `json:"actor_id" db:"actor_id"`
+	// Data holds the details of the change, its shape depends on the type
+	Data    json.RawMessage `json:"data" db:"data"`
+	Created time.Time       `json:"created" db:"created"`
+}
+
+// insertEvent appends an audit event of an elimination in tx, with data as its details
+func insertEvent(ctx context.Context, tx *sqlx.Tx, event Event, data any) error {
+	details, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("failed to encode event data: %w", err)
+	}
+
+	var query = `
+		INSERT INTO elimination_events (
+			id,
+			elimination_id,
+			type,
+			participant_id,
+			actor_id,
+			data,
+			created
+		) VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`
+
+	_, err = tx.ExecContext(
+		ctx,
+		query,
+		util.GenID("event"),
+		event.EliminationID,
+		event.Type,
+		event.ParticipantID,
+		event.ActorID,
+		string(details),
+		event.Created,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to insert event: %w", err)
+	}
+
+	return nil
+}
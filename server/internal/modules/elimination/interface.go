@@ -2,31 +2,75 @@ package elimination
 
 import (
 	"context"
+	"time"
 
 	"github.com/bernardinorafael/globo-challenge/internal/shared/dto"
+	"github.com/bernardinorafael/globo-challenge/pkg/merkle"
 )
 
 type Repository interface {
-	Insert(ctx context.Context, elimination Entity) error
+	Insert(ctx context.Context, elimination Entity, participants []string) error
 	GetAll(ctx context.Context) ([]EntityWithParticipants, error)
 	GetAllOpen(ctx context.Context) ([]EntityWithParticipants, error)
 	GetByID(ctx context.Context, eliminationId string) (*Entity, error)
-	GetUniqueOpen(ctx context.Context) (*Entity, error)
+	SetTieBreakDecision(ctx context.Context, elimination Entity) error
+	OpenScheduled(ctx context.Context, now time.Time) ([]string, error)
+	GetExpired(ctx context.Context, now time.Time) ([]Entity, error)
 	GetByIDWithParticipants(ctx context.Context, eliminationId string) (*EntityWithParticipants, error)
-	InsertVote(ctx context.Context, vote Vote) error
+	GetParticipantIDs(ctx context.Context, eliminationId string) ([]string, error)
+	WalkLedger(ctx context.Context, eliminationId string, fn func(entry LedgerEntry, votes map[string]Vote) bool) error
+	GetLedgerDuplicate(ctx context.Context, eliminationId string) (*LedgerDuplicate, error)
+	GetUnledgeredVote(ctx context.Context, eliminationId string) (string, error)
+	BackfillLedger(ctx context.Context, eliminationId string) (int, error)
+	GetLedgerHead(ctx context.Context, eliminationId string) (*LedgerEntry, error)
+	InsertVote(ctx context.Context, vote Vote) (*Insertion, error)
+	GetVote(ctx context.Context, voteId string) (*Vote, error)
+	GetRejection(ctx context.Context, voteId string) (*Rejection, error)
+	InsertRejection(ctx context.Context, rejection Rejection) error
+	InsertVotes(ctx context.Context, votes []Vote) (*Insertion, error)
+	FlushCounters(ctx context.Context, deltas []CounterDelta) (int, error)
+	HasVerifiedVote(ctx context.Context, eliminationId, userId string) (bool, error)
 	GetResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error)
-	GetTotalVotes(ctx context.Context) (int, error)
-	GetTotalUsers(ctx context.Context) (int, error)
-	FinishElimination(ctx context.Context, eliminationId string, participants []string) error
+	GetFreshResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error)
+	GetTotalVotes(ctx context.Context, eliminationId string) (int, error)
+	GetTotalUsers(ctx context.Context, eliminationId string) (int, error)
+	GetOverview(ctx context.Context) ([]EliminationOverview, error)
+	GetVotesByChannel(ctx context.Context, eliminationId string) (ChannelCounts, error)
+	GetVoteSeries(ctx context.Context, eliminationId string, width time.Duration, timezone string, from, to time.Time) ([]SeriesPoint, error)
+	RebuildCounters(ctx context.Context, eliminationId string) (*CounterRebuild, error)
+	CloseElimination(ctx context.Context, eliminationId string, closedAt time.Time) error
+	MarkDrained(ctx context.Context, eliminationId string) error
+	GetDrained(ctx context.Context, now time.Time, settle, timeout time.Duration) ([]Entity, error)
+	GetSnapshot(ctx context.Context, eliminationId string) ([]ParticipantResult, error)
+	FinishElimination(ctx context.Context, eliminationId string, outcomes []Outcome, snapshot []ParticipantResult, certificate Certificate, tree MerkleTree) error
+	GetCountedVotes(ctx context.Context, eliminationId string) ([]CountedVote, error)
+	GetCertificate(ctx context.Context, eliminationId string) (*Certificate, error)
+	InsertMerkleTree(ctx context.Context, eliminationId string, tree MerkleTree) error
+	GetMerkleLeaf(ctx context.Context, eliminationId, voteId string) (position *int, stored bool, err error)
+	GetMerkleNodes(ctx context.Context, eliminationId string, nodes []merkle.Node) (map[merkle.Node][]byte, error)
+	GetOutcomes(ctx context.Context, eliminationId string) ([]Outcome, error)
+	RemoveParticipant(ctx context.Context, removal Removal) (*Removal, error)
+	GetEvents(ctx context.Context, eliminationId string) ([]Event, error)
 	GetVotesByEliminationID(ctx context.Context, eliminationId string) ([]Vote, error)
 }
 
 type Service interface {
-	CreateElimination(ctx context.Context, participants []string) error
+	CreateElimination(ctx context.Context, input dto.CreateElimination) error
 	GetAll(ctx context.Context) ([]EntityWithParticipants, error)
 	GetAllOpen(ctx context.Context) ([]EntityWithParticipants, error)
-	Vote(ctx context.Context, input dto.CreateVote) error
-	GetResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error)
+	Vote(ctx context.Context, input dto.CreateVote) (*Receipt, error)
+	GetVoteStatus(ctx context.Context, voteId, userId string) (*VoteLookup, error)
+	VerifyReceipt(ctx context.Context, receipt Receipt) ReceiptVerification
+	GetResult(ctx context.Context, eliminationId string, fresh bool) ([]ParticipantResult, error)
+	SubscribeResult(ctx context.Context, eliminationId string) (*ResultEvent, <-chan ResultEvent, func(), error)
 	FinishElimination(ctx context.Context, eliminationId string) error
-	GetDashboard(ctx context.Context) (*DashboardResult, error)
+	FinalizeElimination(ctx context.Context, eliminationId string) error
+	DecideTie(ctx context.Context, eliminationId string, input dto.DecideTie) error
+	GetOutcomes(ctx context.Context, eliminationId string) ([]Outcome, error)
+	RemoveParticipant(ctx context.Context, eliminationId string, input dto.RemoveParticipant) (*Removal, error)
+	GetEvents(ctx context.Context, eliminationId string) ([]Event, error)
+	GetCertificate(ctx context.Context, eliminationId string) (*Certificate, error)
+	GetVoteProof(ctx context.Context, voteId string) (*MerkleProof, error)
+	GetDashboard(ctx context.Context, eliminationId string, input dto.DashboardQuery) (*DashboardResult, error)
+	GetOverview(ctx context.Context) ([]EliminationOverview, error)
 }
@@ -0,0 +1,342 @@
+package elimination
+
+import (
+	"cmp"
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/infra/signer"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+const (
+	// genesisHash is the previous hash of the first ledger entry of an elimination
+	genesisHash = "0000000000000000000000000000000000000000000000000000000000000000"
+	// ledgerLockClass namespaces the advisory locks that serialize the ledger
+	// appends of an elimination
+	ledgerLockClass = 20250412
+	// backfillChunk is the number of votes chained per entry by a backfill
+	backfillChunk = 1000
+)
+
+// LedgerEntry is a link of the hash chain kept for the votes of an elimination
+// Every batch of stored votes appends one entry per elimination, whose hash
+// covers the previous hash and every field of its votes
+type LedgerEntry struct {
+	EliminationID string         `json:"elimination_id" db:"elimination_id"`
+	Seq           int64          `json:"seq" db:"seq"`
+	VoteIDs       pq.StringArray `json:"vote_ids" db:"vote_ids"`
+	PrevHash      string         `json:"prev_hash" db:"prev_hash"`
+	Hash          string         `json:"hash" db:"hash"`
+	Created       time.Time      `json:"created" db:"created"`
+}
+
+// LedgerAudit is the result of walking the ledger of an elimination
+type LedgerAudit struct {
+	EliminationID string `json:"elimination_id"`
+	Entries       int    `json:"entries"`
+	Votes         int    `json:"votes"`
+	// Head is the hash of the last valid entry
+	Head string `json:"head"`
+	// Anchored is the entry whose hash the certificate of the elimination
+	// anchors, zero when it has no certificate yet
+	Anchored int64 `json:"anchored"`
+	// Break describes the first inconsistency found, nil when the chain is intact
+	Break *LedgerBreak `json:"break,omitempty"`
+}
+
+// LedgerDuplicate is the first entry that holds a vote already held by an earlier entry
+type LedgerDuplicate struct {
+	VoteID string `db:"vote_id"`
+	Seq    int64  `db:"seq"`
+}
+
+// LedgerBreak is the first point where the ledger and the stored votes disagree
+type LedgerBreak struct {
+	// Seq is the entry where the chain breaks, zero when a vote is missing from the ledger
+	Seq    int64  `json:"seq"`
+	Reason string `json:"reason"`
+}
+
+// hashEntry returns the hash of a ledger entry
+// Votes are hashed in the order given, which is the order of the entry vote IDs
+func hashEntry(prevHash string, seq int64, eliminationId string, votes []Vote) string {
+	h := sha256.New()
+	h.Write([]byte(prevHash + "\n" + strconv.FormatInt(seq, 10) + "\n" + eliminationId + "\n"))
+	for _, v := range votes {
+		h.Write([]byte(strings.Join([]string{
+			v.ID,
+			v.UserID,
+			v.ParticipantID,
+			v.EliminationID,
+			v.Origin,
+			string(v.Pool),
+			v.Created.UTC().Format(time.RFC3339Nano),
+		}, "|") + "\n"))
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// ledgerHead returns the last entry of the ledger of an elimination, or the
+// genesis hash at entry 0 when the ledger is empty
+func ledgerHead(ctx context.Context, q sqlx.QueryerContext, eliminationId string) (*LedgerEntry, error) {
+	var last = LedgerEntry{EliminationID: eliminationId, Hash: genesisHash}
+	var query = `
+		SELECT * FROM vote_ledger
+		WHERE elimination_id = $1
+		ORDER BY seq DESC
+		LIMIT 1
+	`
+
+	err := sqlx.GetContext(ctx, q, &last, query, eliminationId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get ledger head: %w", err)
+	}
+
+	return &last, nil
+}
+
+// appendLedger appends one entry per elimination for the votes just stored in tx
+// The votes must be read back from the database, so their timestamps are
+// hashed with the precision they are stored with
+func appendLedger(ctx context.Context, tx *sqlx.Tx, votes []Vote) error {
+	var byElimination = make(map[string][]Vote)
+	for _, v := range votes {
+		byElimination[v.EliminationID] = append(byElimination[v.EliminationID], v)
+	}
+
+	// Locks are always taken in the same order so concurrent batches cannot deadlock
+	var eliminationIds = make([]string, 0, len(byElimination))
+	for id := range byElimination {
+		eliminationIds = append(eliminationIds, id)
+	}
+	slices.Sort(eliminationIds)
+
+	for _, eliminationId := range eliminationIds {
+		last, err := lockLedger(ctx, tx, eliminationId)
+		if err != nil {
+			return err
+		}
+
+		batch := byElimination[eliminationId]
+		slices.SortFunc(batch, func(a, b Vote) int { return cmp.Compare(a.ID, b.ID) })
+
+		if _, err := insertLedgerEntry(ctx, tx, last, batch); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// lockLedger locks the ledger of an elimination for the rest of tx and returns its head
+func lockLedger(ctx context.Context, tx *sqlx.Tx, eliminationId string) (*LedgerEntry, error) {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", ledgerLockClass, eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock ledger: %w", err)
+	}
+
+	return ledgerHead(ctx, tx, eliminationId)
+}
+
+// insertLedgerEntry chains the votes of a batch after last, in the order given
+func insertLedgerEntry(ctx context.Context, tx *sqlx.Tx, last *LedgerEntry, batch []Vote) (*LedgerEntry, error) {
+	var voteIds = make([]string, 0, len(batch))
+	for _, v := range batch {
+		voteIds = append(voteIds, v.ID)
+	}
+
+	entry := LedgerEntry{
+		EliminationID: last.EliminationID,
+		Seq:           last.Seq + 1,
+		VoteIDs:       voteIds,
+		PrevHash:      last.Hash,
+		Hash:          hashEntry(last.Hash, last.Seq+1, last.EliminationID, batch),
+		Created:       time.Now(),
+	}
+
+	var query = `
+		INSERT INTO vote_ledger (
+			elimination_id,
+			seq,
+			vote_ids,
+			prev_hash,
+			hash,
+			created
+		) VALUES (
+			:elimination_id,
+			:seq,
+			:vote_ids,
+			:prev_hash,
+			:hash,
+			:created
+		)
+	`
+
+	_, err := tx.NamedExecContext(ctx, query, entry)
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert ledger entry: %w", err)
+	}
+
+	return &entry, nil
+}
+
+// backfillLedger chains the votes an elimination stored before the ledger
+// existed, in the order they were cast, returning how many were chained
+// Only votes cast before the first entry of the ledger are taken, so a vote
+// inserted outside the consumer afterwards is still reported by the audit
+func backfillLedger(ctx context.Context, tx *sqlx.Tx, eliminationId string) (int, error) {
+	last, err := lockLedger(ctx, tx, eliminationId)
+	if err != nil {
+		return 0, err
+	}
+
+	var first sql.NullTime
+	err = tx.GetContext(ctx, &first, "SELECT min(created) FROM vote_ledger WHERE elimination_id = $1", eliminationId)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get first ledger entry: %w", err)
+	}
+	cutoff := time.Now()
+	if first.Valid {
+		cutoff = first.Time
+	}
+
+	// Chained votes leave the selection, so each round reads the next chunk
+	var query = `
+		SELECT * FROM votes
+		WHERE elimination_id = $1
+		AND created < $2
+		AND id NOT IN (SELECT unnest(vote_ids) FROM vote_ledger WHERE elimination_id = $1)
+		ORDER BY created, id
+		LIMIT $3
+	`
+
+	var chained int
+	for {
+		var votes []Vote
+		err := tx.SelectContext(ctx, &votes, query, eliminationId, cutoff, backfillChunk)
+		if err != nil {
+			return chained, fmt.Errorf("failed to get votes missing from the ledger: %w", err)
+		}
+		if len(votes) == 0 {
+			return chained, nil
+		}
+
+		last, err = insertLedgerEntry(ctx, tx, last, votes)
+		if err != nil {
+			return chained, err
+		}
+		chained += len(votes)
+	}
+}
+
+// AuditLedger walks the ledger of an elimination and reports the first entry
+// that does not match the stored votes, an altered or deleted vote breaks the
+// entry that holds it, and a vote added outside the consumer is reported as
+// missing from the ledger
+// A finished elimination is also checked against the head anchored in its
+// signed certificate, so a ledger rewritten from scratch is caught as well
+// The ledger is read one entry at a time, so no elimination is ever held in memory
+func AuditLedger(ctx context.Context, repo Repository, verifier *signer.Signer, eliminationId string) (*LedgerAudit, error) {
+	audit := LedgerAudit{EliminationID: eliminationId, Head: genesisHash}
+
+	certificate, err := repo.GetCertificate(ctx, eliminationId)
+	if err != nil {
+		return nil, err
+	}
+	var anchor CertificateBody
+	if certificate != nil {
+		if !verifier.Verify(certificate.Body, certificate.Signature) {
+			audit.Break = &LedgerBreak{Reason: "certificate signature is invalid"}
+			return &audit, nil
+		}
+		if err := json.Unmarshal(certificate.Body, &anchor); err != nil {
+			return nil, fmt.Errorf("failed to decode certificate: %w", err)
+		}
+		// Certificates issued before ledger heads were anchored have none
+		if anchor.LedgerHead != "" {
+			audit.Anchored = anchor.LedgerEntries
+		}
+	}
+
+	duplicate, err := repo.GetLedgerDuplicate(ctx, eliminationId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = repo.WalkLedger(ctx, eliminationId, func(entry LedgerEntry, stored map[string]Vote) bool {
+		audit.Entries++
+		fail := func(reason string, args ...any) bool {
+			audit.Break = &LedgerBreak{Seq: entry.Seq, Reason: fmt.Sprintf(reason, args...)}
+			return false
+		}
+
+		if entry.Seq != int64(audit.Entries) {
+			return fail("expected entry %d, found entry %d", audit.Entries, entry.Seq)
+		}
+		if entry.PrevHash != audit.Head {
+			return fail("previous hash %s does not match the hash of the previous entry", entry.PrevHash)
+		}
+		if duplicate != nil && entry.Seq == duplicate.Seq {
+			return fail("vote %s appears in more than one entry", duplicate.VoteID)
+		}
+
+		var batch = make([]Vote, 0, len(entry.VoteIDs))
+		for _, id := range entry.VoteIDs {
+			v, ok := stored[id]
+			if !ok {
+				return fail("vote %s was deleted", id)
+			}
+			batch = append(batch, v)
+		}
+
+		if hash := hashEntry(entry.PrevHash, entry.Seq, eliminationId, batch); hash != entry.Hash {
+			return fail("hash does not match its votes, a vote or the entry was altered")
+		}
+		if entry.Seq == audit.Anchored && entry.Hash != anchor.LedgerHead {
+			return fail("hash does not match the head anchored in the certificate")
+		}
+		audit.Head = entry.Hash
+		audit.Votes += len(batch)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+	if audit.Break != nil {
+		return &audit, nil
+	}
+
+	if int64(audit.Entries) < audit.Anchored {
+		audit.Break = &LedgerBreak{
+			Seq:    int64(audit.Entries) + 1,
+			Reason: fmt.Sprintf("ledger ends at entry %d but the certificate anchors entry %d", audit.Entries, audit.Anchored),
+		}
+		return &audit, nil
+	}
+	// An empty ledger is anchored at entry 0 with the genesis hash
+	if audit.Anchored == 0 && anchor.LedgerHead != "" && anchor.LedgerHead != genesisHash {
+		audit.Break = &LedgerBreak{Reason: "certificate anchors a ledger head that is not an entry"}
+		return &audit, nil
+	}
+
+	missing, err := repo.GetUnledgeredVote(ctx, eliminationId)
+	if err != nil {
+		return nil, err
+	}
+	if missing != "" {
+		audit.Break = &LedgerBreak{Reason: fmt.Sprintf("vote %s is not in the ledger", missing)}
+	}
+
+	return &audit, nil
+}
@@ -0,0 +1,69 @@
+package elimination
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+
+	"github.com/bernardinorafael/globo-challenge/pkg/merkle"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// MerkleTree is the tree of a certificate as it is stored, the leaves are the
+// sorted IDs of the counted votes
+type MerkleTree struct {
+	VoteIDs []string
+	Levels  [][][]byte
+}
+
+// newMerkleTree builds the tree of the counted votes of an elimination
+func newMerkleTree(votes []CountedVote) MerkleTree {
+	ids := voteIDs(votes)
+	return MerkleTree{VoteIDs: ids, Levels: merkle.Levels(ids)}
+}
+
+// Root returns the hex encoded root of the tree
+func (t MerkleTree) Root() string {
+	if len(t.Levels) == 0 {
+		return merkle.Root(nil)
+	}
+	return hex.EncodeToString(t.Levels[len(t.Levels)-1][0])
+}
+
+// insertMerkleTree stores every level of the tree of an elimination in tx
+func insertMerkleTree(ctx context.Context, tx *sqlx.Tx, eliminationId string, tree MerkleTree) error {
+	stmt, err := tx.PrepareContext(
+		ctx,
+		pq.CopyIn(
+			"elimination_merkle_nodes",
+			"elimination_id",
+			"level",
+			"position",
+			"hash",
+			"vote_id",
+		),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to prepare copy: %w", err)
+	}
+	defer stmt.Close()
+
+	for level, nodes := range tree.Levels {
+		for position, hash := range nodes {
+			var voteId *string
+			if level == 0 {
+				voteId = &tree.VoteIDs[position]
+			}
+			_, err := stmt.ExecContext(ctx, eliminationId, level, position, hash, voteId)
+			if err != nil {
+				return fmt.Errorf("failed to copy merkle node: %w", err)
+			}
+		}
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return fmt.Errorf("failed to flush copy: %w", err)
+	}
+
+	return nil
+}
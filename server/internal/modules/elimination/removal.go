@@ -0,0 +1,84 @@
+package elimination
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Removal is a participant taken out of an open elimination ("bate-volta")
+// Their votes are annulled, they stay stored and in the ledger but are no
+// longer counted, and the percentages of the others are recomputed
+type Removal struct {
+	EliminationID string `json:"elimination_id"`
+	ParticipantID string `json:"participant_id"`
+	Reason        string `json:"reason"`
+	// ActorID is the user who removed the participant
+	ActorID string `json:"actor_id"`
+	// AnnulledVotes is the number of votes the participant had when removed
+	AnnulledVotes int       `json:"annulled_votes"`
+	Removed       time.Time `json:"removed"`
+}
+
+// removedParticipants returns the participants removed from each elimination,
+// keyed by elimination and participant ID
+func removedParticipants(ctx context.Context, tx *sqlx.Tx, eliminationIds []string) (map[[2]string]bool, error) {
+	var rows []struct {
+		EliminationID string `db:"elimination_id"`
+		ParticipantID string `db:"participant_id"`
+	}
+
+	var query = `
+		SELECT elimination_id, participant_id
+		FROM elimination_participants
+		WHERE elimination_id = ANY($1) AND removed_at IS NOT NULL
+	`
+
+	err := tx.SelectContext(ctx, &rows, query, pq.Array(eliminationIds))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get removed participants: %w", err)
+	}
+
+	var removed = make(map[[2]string]bool, len(rows))
+	for _, row := range rows {
+		removed[[2]string{row.EliminationID, row.ParticipantID}] = true
+	}
+
+	return removed, nil
+}
+
+// releaseParticipant updates the status of a participant leaving an elimination
+// An eliminated participant is marked as such, any other stays in elimination
+// while another elimination poll they are still in is not finished
+func releaseParticipant(ctx context.Context, tx *sqlx.Tx, eliminationId, participantId string, eliminated bool) error {
+	var query = `
+		UPDATE participants p SET
+			status = CASE WHEN $2 THEN 'eliminated' ELSE 'active' END,
+			updated = now()
+		WHERE p.id = $1 AND (
+			$2 OR (
+				p.status = 'in_elimination'
+				AND NOT EXISTS (
+					SELECT 1
+					FROM elimination_participants ep
+					JOIN eliminations e ON e.id = ep.elimination_id
+					WHERE ep.participant_id = p.id
+						AND ep.removed_at IS NULL
+						AND e.id <> $3
+						AND e.type = 'elimination'
+						AND e.status <> 'closed'
+				)
+			)
+		)
+	`
+
+	_, err := tx.ExecContext(ctx, query, participantId, eliminated, eliminationId)
+	if err != nil {
+		return fmt.Errorf("failed to update participant: %w", err)
+	}
+
+	return nil
+}
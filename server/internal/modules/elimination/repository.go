@@ -4,11 +4,16 @@ import (
 	"context"
 	"database/sql"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"slices"
 	"time"
 
+	"github.com/bernardinorafael/globo-challenge/internal/modules/participant"
 	"github.com/bernardinorafael/globo-challenge/internal/util"
+	"github.com/bernardinorafael/globo-challenge/pkg/merkle"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 )
 
 type repository struct {
@@ -21,144 +26,1318 @@ func NewRepository(db *sqlx.DB) Repository {
 	}
 }
 
+// GetVotesByEliminationID returns every vote stored for an elimination
+// It reads the whole elimination for the ledger audit, hence the long timeout
 func (r repository) GetVotesByEliminationID(ctx context.Context, eliminationId string) ([]Vote, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	var votes []Vote
+	err := r.db.SelectContext(ctx, &votes, "SELECT * FROM votes WHERE elimination_id = $1", eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get votes: %w", err)
+	}
+
+	return votes, nil
+}
+
+// OpenScheduled opens every scheduled elimination whose start date has been
+// reached, returning the IDs of the opened eliminations
+func (r repository) OpenScheduled(ctx context.Context, now time.Time) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		UPDATE eliminations SET
+			open = true,
+			status = 'open',
+			updated = now()
+		WHERE status = 'scheduled' AND start_date <= $1
+		RETURNING id
+	`
+
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids, query, now)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open scheduled eliminations: %w", err)
+	}
+
+	return ids, nil
+}
+
+// GetExpired returns every open elimination whose end date has been reached
+func (r repository) GetExpired(ctx context.Context, now time.Time) ([]Entity, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var eliminations []Entity
+	err := r.db.SelectContext(
+		ctx,
+		&eliminations,
+		"SELECT * FROM eliminations WHERE status = 'open' AND end_date <= $1",
+		now,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get expired eliminations: %w", err)
+	}
+
+	return eliminations, nil
+}
+
+func (r repository) GetVotesByChannel(ctx context.Context, eliminationId string) (ChannelCounts, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT
+			COALESCE(json_object_agg(c.origin, c.count), '{}') AS "votes_by_channel"
+		FROM (
+			SELECT origin, SUM(count) AS count
+			FROM vote_counters
+			WHERE elimination_id = $1
+			GROUP BY origin
+		) c
+	`
+
+	var result ChannelCounts
+	err := r.db.GetContext(ctx, &result, query, eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get votes by channel: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetVoteSeries returns the votes of an elimination from the vote counters in
+// buckets of the given width between from and to, aligned to the timezone
+// Every bucket of the window is returned, the ones without votes with zero
+func (r repository) GetVoteSeries(
+	ctx context.Context,
+	eliminationId string,
+	width time.Duration,
+	timezone string,
+	from, to time.Time,
+) ([]SeriesPoint, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	// Buckets are binned in local time, so an hour bucket starts at a full
+	// hour of the timezone even when its offset is not a whole hour
+	var query = `
+		WITH counts AS (
+			SELECT
+				date_bin(make_interval(secs => $2), bucket AT TIME ZONE $3, '2000-01-01') AS local_start,
+				SUM(count) AS votes
+			FROM vote_counters
+			WHERE elimination_id = $1
+			GROUP BY 1
+		)
+		SELECT
+			s.local_start AT TIME ZONE $3 AS "start",
+			COALESCE(c.votes, 0)::bigint AS "votes"
+		FROM generate_series(
+			date_bin(make_interval(secs => $2), $4::timestamptz AT TIME ZONE $3, '2000-01-01'),
+			$5::timestamptz AT TIME ZONE $3,
+			make_interval(secs => $2)
+		) AS s(local_start)
+		LEFT JOIN counts c ON c.local_start = s.local_start
+		ORDER BY s.local_start
+	`
+
+	var series []SeriesPoint
+	err := r.db.SelectContext(ctx, &series, query, eliminationId, width.Seconds(), timezone, from, to)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get vote series: %w", err)
+	}
+
+	return series, nil
+}
+
+// GetTotalUsers returns the distinct users who voted in an elimination
+func (r repository) GetTotalUsers(ctx context.Context, eliminationId string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT
+			COUNT(user_id) AS "total_users"
+		FROM elimination_voters
+		WHERE elimination_id = $1
+	`
+
+	var result int
+	err := r.db.GetContext(ctx, &result, query, eliminationId)
+	if err != nil {
+		return -1, fmt.Errorf("failed to get total users: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetTotalVotes returns the votes of an elimination from the vote counters
+func (r repository) GetTotalVotes(ctx context.Context, eliminationId string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT
+			COALESCE(SUM(count), 0) AS "total_votes"
+		FROM vote_counters
+		WHERE elimination_id = $1
+	`
+
+	var result int
+	err := r.db.GetContext(ctx, &result, query, eliminationId)
+	if err != nil {
+		return -1, fmt.Errorf("failed to get total votes: %w", err)
+	}
+
+	return result, nil
+}
+
+// GetOverview returns the vote totals of every elimination, ordered by start date
+func (r repository) GetOverview(ctx context.Context) ([]EliminationOverview, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT
+			e.id AS "elimination_id",
+			e.type AS "type",
+			e.status AS "status",
+			e.start_date AS "start_date",
+			e.end_date AS "end_date",
+			e.closed_at AS "closed_at",
+			COALESCE(t.votes, 0)::bigint AS "total_votes",
+			COALESCE(v.voters, 0) AS "unique_voters",
+			COALESCE(p.peak, 0)::bigint AS "peak_votes_per_minute"
+		FROM eliminations e
+		LEFT JOIN (
+			SELECT elimination_id, SUM(count) AS votes
+			FROM vote_counters
+			GROUP BY elimination_id
+		) t ON t.elimination_id = e.id
+		LEFT JOIN (
+			SELECT elimination_id, COUNT(user_id) AS voters
+			FROM elimination_voters
+			GROUP BY elimination_id
+		) v ON v.elimination_id = e.id
+		LEFT JOIN (
+			SELECT elimination_id, MAX(votes) AS peak
+			FROM (
+				SELECT elimination_id, bucket, SUM(count) AS votes
+				FROM vote_counters
+				GROUP BY elimination_id, bucket
+			) b
+			GROUP BY elimination_id
+		) p ON p.elimination_id = e.id
+		ORDER BY e.start_date
+	`
+
+	var overview []EliminationOverview
+	err := r.db.SelectContext(ctx, &overview, query)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get overview: %w", err)
+	}
+
+	return overview, nil
+}
+
+// RebuildCounters recomputes the vote counters and voters of an elimination
+// from its stored votes, without the annulled ones, returning the vote total
+// before and after
+// The ledger lock of the elimination is held so no vote is stored meanwhile,
+// and a new counter epoch is started
+func (r repository) RebuildCounters(ctx context.Context, eliminationId string) (*CounterRebuild, error) {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	rebuild := CounterRebuild{EliminationID: eliminationId}
+
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", ledgerLockClass, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to lock ledger: %w", err)
+		}
+
+		var query = `
+			SELECT COALESCE(SUM(count), 0)
+			FROM vote_counters
+			WHERE elimination_id = $1
+		`
+
+		err = tx.GetContext(ctx, &rebuild.Before, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to get counted votes: %w", err)
+		}
+
+		// The votes tallied in memory and not flushed yet are part of the rebuilt
+		// counters, starting a new epoch makes their flush a no-op
+		query = `
+			INSERT INTO vote_counter_epochs (elimination_id, epoch)
+			VALUES ($1, 1)
+			ON CONFLICT (elimination_id) DO UPDATE SET
+				epoch = vote_counter_epochs.epoch + 1
+			RETURNING epoch
+		`
+
+		err = tx.GetContext(ctx, &rebuild.Epoch, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to start counter epoch: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM vote_counters WHERE elimination_id = $1", eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to delete vote counters: %w", err)
+		}
+
+		query = `
+			INSERT INTO vote_counters (
+				elimination_id,
+				participant_id,
+				origin,
+				pool,
+				bucket,
+				count,
+				reached_at
+			)
+			SELECT
+				elimination_id,
+				participant_id,
+				origin,
+				pool,
+				date_trunc('minute', created),
+				COUNT(id),
+				MAX(created)
+			FROM votes
+			WHERE elimination_id = $1
+			AND participant_id NOT IN (
+				SELECT participant_id
+				FROM elimination_participants
+				WHERE elimination_id = $1 AND removed_at IS NOT NULL
+			)
+			GROUP BY elimination_id, participant_id, origin, pool, date_trunc('minute', created)
+		`
+
+		_, err = tx.ExecContext(ctx, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild vote counters: %w", err)
+		}
+
+		_, err = tx.ExecContext(ctx, "DELETE FROM elimination_voters WHERE elimination_id = $1", eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to delete voters: %w", err)
+		}
+
+		query = `
+			INSERT INTO elimination_voters (elimination_id, user_id)
+			SELECT DISTINCT elimination_id, user_id
+			FROM votes
+			WHERE elimination_id = $1
+			AND participant_id NOT IN (
+				SELECT participant_id
+				FROM elimination_participants
+				WHERE elimination_id = $1 AND removed_at IS NOT NULL
+			)
+		`
+
+		_, err = tx.ExecContext(ctx, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to rebuild voters: %w", err)
+		}
+
+		query = `
+			SELECT COALESCE(SUM(count), 0)
+			FROM vote_counters
+			WHERE elimination_id = $1
+		`
+
+		err = tx.GetContext(ctx, &rebuild.After, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to get counted votes: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to rebuild counters: %w", err)
+	}
+
+	return &rebuild, nil
+}
+
+// FlushCounters adds tallied deltas to the vote counters in a single
+// transaction and notifies the result streams of every elimination counted
+// Deltas of a previous counter epoch or of a removed participant are dropped,
+// their count is returned
+func (r repository) FlushCounters(ctx context.Context, deltas []CounterDelta) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	var byElimination = make(map[string][]CounterDelta)
+	for _, d := range deltas {
+		byElimination[d.EliminationID] = append(byElimination[d.EliminationID], d)
+	}
+
+	// Locks are always taken in the same order so concurrent flushes cannot deadlock
+	var eliminationIds = make([]string, 0, len(byElimination))
+	for id := range byElimination {
+		eliminationIds = append(eliminationIds, id)
+	}
+	slices.Sort(eliminationIds)
+
+	var dropped int
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		dropped = 0
+
+		for _, eliminationId := range eliminationIds {
+			// The ledger lock keeps a rebuild from starting a new epoch mid-flush
+			_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", ledgerLockClass, eliminationId)
+			if err != nil {
+				return fmt.Errorf("failed to lock ledger: %w", err)
+			}
+		}
+
+		epochs, err := counterEpochs(ctx, tx, eliminationIds)
+		if err != nil {
+			return err
+		}
+		removed, err := removedParticipants(ctx, tx, eliminationIds)
+		if err != nil {
+			return err
+		}
+
+		var query = `
+			INSERT INTO vote_counters (
+				elimination_id,
+				participant_id,
+				origin,
+				pool,
+				bucket,
+				count,
+				reached_at
+			) VALUES (
+				:elimination_id,
+				:participant_id,
+				:origin,
+				:pool,
+				:bucket,
+				:count,
+				:reached_at
+			)
+			ON CONFLICT (elimination_id, participant_id, origin, pool, bucket) DO UPDATE SET
+				count = vote_counters.count + EXCLUDED.count,
+				reached_at = GREATEST(vote_counters.reached_at, EXCLUDED.reached_at)
+		`
+
+		for _, eliminationId := range eliminationIds {
+			var counted bool
+			for _, d := range byElimination[eliminationId] {
+				if d.Epoch != epochs[eliminationId] || removed[[2]string{eliminationId, d.ParticipantID}] {
+					dropped++
+					continue
+				}
+
+				_, err := tx.NamedExecContext(ctx, query, d)
+				if err != nil {
+					return fmt.Errorf("failed to add vote counter delta: %w", err)
+				}
+				counted = true
+			}
+			if !counted {
+				continue
+			}
+
+			// Notifications are only delivered once the transaction commits
+			_, err = tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", votesChannel, eliminationId)
+			if err != nil {
+				return fmt.Errorf("failed to notify votes: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return 0, fmt.Errorf("failed to flush vote counters: %w", err)
+	}
+
+	return dropped, nil
+}
+
+// GetFreshResult counts the votes of each participant of an elimination from
+// the votes table, including the ones not flushed to the counters yet
+func (r repository) GetFreshResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var participants []ParticipantResult
+
+	var query = `
+		SELECT
+			p.id AS "id",
+			p.name AS "name",
+			COALESCE(SUM(c.count), 0) AS "count",
+			COALESCE(SUM(c.verified_count), 0) AS "verified_count",
+			COALESCE(SUM(c.fan_count), 0) AS "fan_count",
+			MAX(c.reached_at) AS "reached_at",
+			COALESCE(
+				json_object_agg(c.origin, c.count) FILTER (WHERE c.origin IS NOT NULL),
+				'{}'
+			) AS "channels"
+		FROM elimination_participants ep
+		JOIN participants p ON p.id = ep.participant_id
+		LEFT JOIN (
+			SELECT
+				participant_id,
+				origin,
+				COUNT(id) AS count,
+				COUNT(id) FILTER (WHERE pool = 'verified') AS verified_count,
+				COUNT(id) FILTER (WHERE pool = 'fan') AS fan_count,
+				MAX(created) AS reached_at
+			FROM votes
+			WHERE elimination_id = $1
+			AND created < COALESCE(
+				(SELECT closed_at FROM eliminations WHERE id = $1),
+				'infinity'
+			)
+			GROUP BY participant_id, origin
+		) c ON c.participant_id = p.id
+		WHERE ep.elimination_id = $1 AND ep.removed_at IS NULL
+		GROUP BY p.id, p.name
+		ORDER BY "count" DESC
+	`
+
+	err := r.db.SelectContext(ctx, &participants, query, eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get votes: %w", err)
+	}
+
+	return participants, nil
+}
+
+// CloseElimination stops an elimination from accepting votes cast from closedAt on
+// The elimination stays closing until its queued votes are drained
+func (r repository) CloseElimination(ctx context.Context, eliminationId string, closedAt time.Time) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		UPDATE eliminations SET
+			open = false,
+			status = 'closing',
+			closed_at = $2,
+			updated = now()
+		WHERE id = $1 AND status IN ('scheduled', 'open')
+	`
+
+	res, err := r.db.ExecContext(ctx, query, eliminationId, closedAt)
+	if err != nil {
+		return fmt.Errorf("failed to close elimination: %w", err)
+	}
+	if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+		return fmt.Errorf("elimination %s is already closed", eliminationId)
+	}
+
+	return nil
+}
+
+// MarkDrained records that the consumer reached the close barrier of an elimination
+func (r repository) MarkDrained(ctx context.Context, eliminationId string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		UPDATE eliminations SET
+			drained_at = now(),
+			updated = now()
+		WHERE id = $1 AND status = 'closing' AND drained_at IS NULL
+	`
+
+	_, err := r.db.ExecContext(ctx, query, eliminationId)
+	if err != nil {
+		return fmt.Errorf("failed to mark elimination as drained: %w", err)
+	}
+
+	return nil
+}
+
+// GetDrained returns the closing eliminations ready to be finalized, the ones
+// drained at least settle ago and the ones closed at least timeout ago
+func (r repository) GetDrained(ctx context.Context, now time.Time, settle, timeout time.Duration) ([]Entity, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT * FROM eliminations
+		WHERE status = 'closing'
+		AND (drained_at <= $1 OR closed_at <= $2)
+	`
+
+	var eliminations []Entity
+	err := r.db.SelectContext(ctx, &eliminations, query, now.Add(-settle), now.Add(-timeout))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get drained eliminations: %w", err)
+	}
+
+	return eliminations, nil
+}
+
+// GetSnapshot returns the result frozen when an elimination was finished, or
+// nil when the elimination has none
+func (r repository) GetSnapshot(ctx context.Context, eliminationId string) ([]ParticipantResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var data []byte
+	err := r.db.GetContext(
+		ctx,
+		&data,
+		"SELECT results FROM elimination_snapshots WHERE elimination_id = $1",
+		eliminationId,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get snapshot: %w", err)
+	}
+
+	var results []ParticipantResult
+	if err := json.Unmarshal(data, &results); err != nil {
+		return nil, fmt.Errorf("failed to decode snapshot: %w", err)
+	}
+
+	return results, nil
+}
+
+// FinishElimination closes a drained elimination, freezes its result into a
+// snapshot and stores its outcome, certificate and Merkle tree, releasing its
+// participants and marking the eliminated ones
+func (r repository) FinishElimination(
+	ctx context.Context,
+	eliminationId string,
+	outcomes []Outcome,
+	snapshot []ParticipantResult,
+	certificate Certificate,
+	tree MerkleTree,
+) error {
+	// The tree holds about two nodes per counted vote
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		return fmt.Errorf("failed to encode snapshot: %w", err)
+	}
+
+	err = util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var query = `
+			UPDATE eliminations SET
+				open = false,
+				status = 'closed',
+				updated = now()
+			WHERE id = $1 AND status = 'closing'
+		`
+
+		res, err := tx.ExecContext(ctx, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to update elimination: %w", err)
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("elimination %s is not closing", eliminationId)
+		}
+
+		query = `
+			INSERT INTO elimination_snapshots (
+				elimination_id,
+				results,
+				created
+			) VALUES ($1, $2, now())
+		`
+
+		_, err = tx.ExecContext(ctx, query, eliminationId, data)
+		if err != nil {
+			return fmt.Errorf("failed to insert snapshot: %w", err)
+		}
+
+		query = `
+			INSERT INTO elimination_certificates (
+				elimination_id,
+				body,
+				signature,
+				created
+			) VALUES ($1, $2, $3, now())
+		`
+
+		_, err = tx.ExecContext(ctx, query, eliminationId, string(certificate.Body), certificate.Signature)
+		if err != nil {
+			return fmt.Errorf("failed to insert certificate: %w", err)
+		}
+
+		err = insertMerkleTree(ctx, tx, eliminationId, tree)
+		if err != nil {
+			return err
+		}
+
+		for _, outcome := range outcomes {
+			var query = `
+				INSERT INTO elimination_outcomes (
+					elimination_id,
+					participant_id,
+					votes,
+					percentage,
+					eliminated,
+					winner,
+					tied
+				) VALUES (
+					:elimination_id,
+					:participant_id,
+					:votes,
+					:percentage,
+					:eliminated,
+					:winner,
+					:tied
+				)
+			`
+
+			_, err := tx.NamedExecContext(ctx, query, outcome)
+			if err != nil {
+				return fmt.Errorf("failed to insert outcome: %w", err)
+			}
+
+			err = releaseParticipant(ctx, tx, eliminationId, outcome.ParticipantID, outcome.Eliminated)
+			if err != nil {
+				return err
+			}
+		}
+
+		_, err = tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", votesChannel, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to notify elimination: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return fmt.Errorf("failed to finish elimination: %w", err)
+	}
+
+	return nil
+}
+
+func (r repository) GetOutcomes(ctx context.Context, eliminationId string) ([]Outcome, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT
+			o.elimination_id,
+			o.participant_id,
+			p.name,
+			o.votes,
+			o.percentage,
+			o.eliminated,
+			o.winner,
+			o.tied
+		FROM elimination_outcomes o
+		JOIN participants p ON p.id = o.participant_id
+		WHERE o.elimination_id = $1
+		ORDER BY o.votes DESC
+	`
+
+	var outcomes = []Outcome{}
+	err := r.db.SelectContext(ctx, &outcomes, query, eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get outcomes: %w", err)
+	}
+
+	return outcomes, nil
+}
+
+// RemoveParticipant takes a participant out of an open elimination, annulling
+// their votes, and records the removal as an audit event
+// The ledger lock is held so the annulled count matches the stored votes, and
+// the counters of the participant are dropped with their votes
+func (r repository) RemoveParticipant(ctx context.Context, removal Removal) (*Removal, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
+	defer cancel()
+
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", ledgerLockClass, removal.EliminationID)
+		if err != nil {
+			return fmt.Errorf("failed to lock ledger: %w", err)
+		}
+
+		var status Status
+		err = tx.GetContext(ctx, &status, "SELECT status FROM eliminations WHERE id = $1 FOR UPDATE", removal.EliminationID)
+		if err != nil {
+			return fmt.Errorf("failed to get elimination: %w", err)
+		}
+		if status != StatusOpen {
+			return fmt.Errorf("elimination %s is not open", removal.EliminationID)
+		}
+
+		var query = `
+			UPDATE elimination_participants SET
+				removed_at = $3
+			WHERE elimination_id = $1 AND participant_id = $2 AND removed_at IS NULL
+		`
+
+		res, err := tx.ExecContext(ctx, query, removal.EliminationID, removal.ParticipantID, removal.Removed)
+		if err != nil {
+			return fmt.Errorf("failed to remove participant: %w", err)
+		}
+		if affected, err := res.RowsAffected(); err != nil || affected == 0 {
+			return fmt.Errorf("participant %s is not in elimination %s", removal.ParticipantID, removal.EliminationID)
+		}
+
+		query = `
+			SELECT COUNT(id)
+			FROM votes
+			WHERE elimination_id = $1 AND participant_id = $2
+		`
+
+		err = tx.GetContext(ctx, &removal.AnnulledVotes, query, removal.EliminationID, removal.ParticipantID)
+		if err != nil {
+			return fmt.Errorf("failed to count annulled votes: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"DELETE FROM vote_counters WHERE elimination_id = $1 AND participant_id = $2",
+			removal.EliminationID,
+			removal.ParticipantID,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to delete vote counters: %w", err)
+		}
+
+		// A manual decision for the removed participant no longer settles anything
+		query = `
+			UPDATE eliminations SET
+				tie_break_decision = null,
+				updated = now()
+			WHERE id = $1 AND tie_break_decision = $2
+		`
+
+		_, err = tx.ExecContext(ctx, query, removal.EliminationID, removal.ParticipantID)
+		if err != nil {
+			return fmt.Errorf("failed to clear tie-break decision: %w", err)
+		}
+
+		err = releaseParticipant(ctx, tx, removal.EliminationID, removal.ParticipantID, false)
+		if err != nil {
+			return err
+		}
+
+		err = insertEvent(ctx, tx, Event{
+			EliminationID: removal.EliminationID,
+			Type:          EventParticipantRemoved,
+			ParticipantID: &removal.ParticipantID,
+			ActorID:       removal.ActorID,
+			Created:       removal.Removed,
+		}, removal)
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, "SELECT pg_notify($1, $2)", votesChannel, removal.EliminationID)
+		if err != nil {
+			return fmt.Errorf("failed to notify elimination: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove participant: %w", err)
+	}
+
+	return &removal, nil
+}
+
+// GetEvents returns the audit events of an elimination, oldest first
+func (r repository) GetEvents(ctx context.Context, eliminationId string) ([]Event, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var events = []Event{}
+	err := r.db.SelectContext(
+		ctx,
+		&events,
+		"SELECT * FROM elimination_events WHERE elimination_id = $1 ORDER BY created",
+		eliminationId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get events: %w", err)
+	}
+
+	return events, nil
+}
+
+// GetResult sums the vote counters of each participant of an elimination,
+// ignoring the votes cast after its close barrier
+// The counters of the bucket the barrier falls in also hold later votes, so
+// that bucket is counted from the votes table instead
+func (r repository) GetResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var participants []ParticipantResult
+
+	var query = `
+		WITH e AS (
+			SELECT COALESCE(closed_at, 'infinity') AS closed_at
+			FROM eliminations
+			WHERE id = $1
+		)
+		SELECT
+			p.id AS "id",
+			p.name AS "name",
+			COALESCE(SUM(c.count), 0) AS "count",
+			COALESCE(SUM(c.verified_count), 0) AS "verified_count",
+			COALESCE(SUM(c.fan_count), 0) AS "fan_count",
+			MAX(c.reached_at) AS "reached_at",
+			COALESCE(
+				json_object_agg(c.origin, c.count) FILTER (WHERE c.origin IS NOT NULL),
+				'{}'
+			) AS "channels"
+		FROM elimination_participants ep
+		JOIN participants p ON p.id = ep.participant_id
+		LEFT JOIN (
+			SELECT
+				participant_id,
+				origin,
+				SUM(count)::bigint AS count,
+				COALESCE(SUM(count) FILTER (WHERE pool = 'verified'), 0)::bigint AS verified_count,
+				COALESCE(SUM(count) FILTER (WHERE pool = 'fan'), 0)::bigint AS fan_count,
+				MAX(reached_at) AS reached_at
+			FROM (
+				SELECT vc.participant_id, vc.origin, vc.pool, vc.count, vc.reached_at
+				FROM vote_counters vc, e
+				WHERE vc.elimination_id = $1
+				AND vc.bucket < date_trunc('minute', e.closed_at)
+				UNION ALL
+				SELECT v.participant_id, v.origin, v.pool, 1, v.created
+				FROM votes v, e
+				WHERE v.elimination_id = $1
+				AND v.created >= date_trunc('minute', e.closed_at)
+				AND v.created < e.closed_at
+			) counted
+			GROUP BY participant_id, origin
+		) c ON c.participant_id = p.id
+		WHERE ep.elimination_id = $1 AND ep.removed_at IS NULL
+		GROUP BY p.id, p.name
+		ORDER BY "count" DESC
+	`
+
+	err := r.db.SelectContext(ctx, &participants, query, eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get votes: %w", err)
+	}
+
+	return participants, nil
+}
+
+// GetCountedVotes returns the votes counted in the result of an elimination,
+// the ones cast before its close barrier for a participant still in it
+func (r repository) GetCountedVotes(ctx context.Context, eliminationId string) ([]CountedVote, error) {
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT v.id, v.participant_id
+		FROM votes v
+		JOIN eliminations e ON e.id = v.elimination_id
+		JOIN elimination_participants ep ON ep.elimination_id = v.elimination_id AND ep.participant_id = v.participant_id
+		WHERE v.elimination_id = $1
+		AND v.created < COALESCE(e.closed_at, 'infinity')
+		AND ep.removed_at IS NULL
+	`
+
+	var votes []CountedVote
+	err := r.db.SelectContext(ctx, &votes, query, eliminationId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get counted votes: %w", err)
+	}
+
+	return votes, nil
+}
+
+// GetCertificate returns the certificate of a finished elimination, or nil
+// when the elimination has none
+func (r repository) GetCertificate(ctx context.Context, eliminationId string) (*Certificate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var certificate Certificate
+	err := r.db.GetContext(
+		ctx,
+		&certificate,
+		"SELECT * FROM elimination_certificates WHERE elimination_id = $1",
+		eliminationId,
+	)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get certificate: %w", err)
+	}
+
+	return &certificate, nil
+}
+
+// InsertMerkleTree stores the Merkle tree of a certificate issued before trees
+// were stored, a tree stored in the meantime is kept
+func (r repository) InsertMerkleTree(ctx context.Context, eliminationId string, tree MerkleTree) error {
+	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
+	defer cancel()
+
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		// Locking the certificate keeps concurrent requests from storing the tree twice
+		var query = `
+			SELECT EXISTS (
+				SELECT 1
+				FROM elimination_merkle_nodes
+				WHERE elimination_id = $1
+			)
+			FROM elimination_certificates
+			WHERE elimination_id = $1
+			FOR UPDATE
+		`
+
+		var stored bool
+		err := tx.GetContext(ctx, &stored, query, eliminationId)
+		if err != nil {
+			return fmt.Errorf("failed to lock certificate: %w", err)
+		}
+		if stored {
+			return nil
+		}
+
+		return insertMerkleTree(ctx, tx, eliminationId, tree)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to insert merkle tree: %w", err)
+	}
+
+	return nil
+}
+
+// GetMerkleLeaf returns the position of a vote among the leaves of the stored
+// tree of its elimination, stored tells whether the elimination has a tree
+func (r repository) GetMerkleLeaf(ctx context.Context, eliminationId, voteId string) (position *int, stored bool, err error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		SELECT
+			(
+				SELECT position
+				FROM elimination_merkle_nodes
+				WHERE elimination_id = $1 AND vote_id = $2
+			) AS "position",
+			EXISTS (
+				SELECT 1
+				FROM elimination_merkle_nodes
+				WHERE elimination_id = $1
+			) AS "stored"
+	`
+
+	var leaf struct {
+		Position *int `db:"position"`
+		Stored   bool `db:"stored"`
+	}
+	err = r.db.GetContext(ctx, &leaf, query, eliminationId, voteId)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to get merkle leaf: %w", err)
+	}
+
+	return leaf.Position, leaf.Stored, nil
+}
+
+// GetMerkleNodes returns the hashes of the given nodes of the stored tree of an elimination
+func (r repository) GetMerkleNodes(ctx context.Context, eliminationId string, nodes []merkle.Node) (map[merkle.Node][]byte, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var levels, positions = make([]int64, 0, len(nodes)), make([]int64, 0, len(nodes))
+	for _, n := range nodes {
+		levels = append(levels, int64(n.Level))
+		positions = append(positions, int64(n.Position))
+	}
+
+	var query = `
+		SELECT level, position, hash
+		FROM elimination_merkle_nodes
+		WHERE elimination_id = $1
+		AND (level, position) IN (
+			SELECT * FROM unnest($2::integer[], $3::integer[])
+		)
+	`
+
+	var rows []struct {
+		Level    int    `db:"level"`
+		Position int    `db:"position"`
+		Hash     []byte `db:"hash"`
+	}
+	err := r.db.SelectContext(ctx, &rows, query, eliminationId, pq.Array(levels), pq.Array(positions))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get merkle nodes: %w", err)
+	}
+
+	var hashes = make(map[merkle.Node][]byte, len(rows))
+	for _, row := range rows {
+		hashes[merkle.Node{Level: row.Level, Position: row.Position}] = row.Hash
+	}
+
+	return hashes, nil
+}
+
+// GetVote returns a stored vote, or nil when it was not stored
+func (r repository) GetVote(ctx context.Context, voteId string) (*Vote, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	var votes []Vote
-	err := r.db.SelectContext(ctx, &votes, "SELECT * FROM votes WHERE elimination_id = $1", eliminationId)
+	var vote Vote
+	err := r.db.GetContext(ctx, &vote, "SELECT * FROM votes WHERE id = $1", voteId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get votes: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get vote: %w", err)
 	}
 
-	return votes, nil
+	return &vote, nil
 }
 
-func (r repository) GetUniqueOpen(ctx context.Context) (*Entity, error) {
+// GetRejection returns why a vote was rejected, or nil when it was not
+func (r repository) GetRejection(ctx context.Context, voteId string) (*Rejection, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	var elimination Entity
-	err := r.db.GetContext(ctx, &elimination, "SELECT * FROM eliminations WHERE open = true LIMIT 1")
+	var rejection Rejection
+	err := r.db.GetContext(ctx, &rejection, "SELECT * FROM vote_rejections WHERE vote_id = $1", voteId)
 	if err != nil {
-		if err == sql.ErrNoRows {
+		if errors.Is(err, sql.ErrNoRows) {
 			return nil, nil
 		}
-		return nil, fmt.Errorf("failed to get unique open elimination: %w", err)
+		return nil, fmt.Errorf("failed to get vote rejection: %w", err)
 	}
 
-	return &elimination, nil
+	return &rejection, nil
 }
 
-func (r repository) GetTotalUsers(ctx context.Context) (int, error) {
+// InsertRejection records a rejected vote, a redelivered rejection is ignored
+func (r repository) InsertRejection(ctx context.Context, rejection Rejection) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	var query = `
-		SELECT
-			COUNT(DISTINCT v.user_id) AS "total_users"
-		FROM votes v
-		LEFT JOIN eliminations e ON e.id = v.elimination_id
-		WHERE e.open = true
+		INSERT INTO vote_rejections (
+			vote_id,
+			user_id,
+			elimination_id,
+			code,
+			reason,
+			created
+		) VALUES (
+			:vote_id,
+			:user_id,
+			:elimination_id,
+			:code,
+			:reason,
+			:created
+		)
+		ON CONFLICT (vote_id) DO NOTHING
 	`
 
-	var result int
-	err := r.db.GetContext(ctx, &result, query)
+	_, err := r.db.NamedExecContext(ctx, query, rejection)
 	if err != nil {
-		return -1, fmt.Errorf("failed to get total users: %w", err)
+		return fmt.Errorf("failed to insert vote rejection: %w", err)
 	}
 
-	return result, nil
+	return nil
 }
 
-func (r repository) GetTotalVotes(ctx context.Context) (int, error) {
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+// WalkLedger calls fn with every entry of the ledger of an elimination in
+// order, along with the stored votes the entry holds, until fn returns false
+// Entries are read through a cursor, so the ledger is never held in memory at once
+func (r repository) WalkLedger(ctx context.Context, eliminationId string, fn func(entry LedgerEntry, votes map[string]Vote) bool) error {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
+
+	rows, err := r.db.QueryxContext(
+		ctx,
+		"SELECT * FROM vote_ledger WHERE elimination_id = $1 ORDER BY seq",
+		eliminationId,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to get ledger: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entry LedgerEntry
+		if err := rows.StructScan(&entry); err != nil {
+			return fmt.Errorf("failed to scan ledger entry: %w", err)
+		}
+
+		var votes []Vote
+		err := r.db.SelectContext(ctx, &votes, "SELECT * FROM votes WHERE id = ANY($1)", entry.VoteIDs)
+		if err != nil {
+			return fmt.Errorf("failed to get ledger entry votes: %w", err)
+		}
+		var stored = make(map[string]Vote, len(votes))
+		for _, v := range votes {
+			stored[v.ID] = v
+		}
+
+		if !fn(entry, stored) {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("failed to read ledger: %w", err)
+	}
+
+	return nil
+}
+
+// GetLedgerDuplicate returns the first entry of the ledger of an elimination
+// that holds a vote an earlier entry already holds, nil when there is none
+func (r repository) GetLedgerDuplicate(ctx context.Context, eliminationId string) (*LedgerDuplicate, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
 	var query = `
-		SELECT
-			COUNT(v.id) AS "total_votes"
-		FROM votes v
-		LEFT JOIN eliminations e ON e.id = v.elimination_id
-		WHERE e.open = true
+		SELECT vote_id, seq FROM (
+			SELECT
+				u.vote_id,
+				l.seq,
+				row_number() OVER (PARTITION BY u.vote_id ORDER BY l.seq) AS "n"
+			FROM vote_ledger l
+			CROSS JOIN unnest(l.vote_ids) AS u(vote_id)
+			WHERE l.elimination_id = $1
+		) d
+		WHERE n > 1
+		ORDER BY seq
+		LIMIT 1
 	`
 
-	var result int
-	err := r.db.GetContext(ctx, &result, query)
+	var duplicate LedgerDuplicate
+	err := r.db.GetContext(ctx, &duplicate, query, eliminationId)
 	if err != nil {
-		return -1, fmt.Errorf("failed to get total votes: %w", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to get ledger duplicate: %w", err)
 	}
 
-	return result, nil
+	return &duplicate, nil
 }
 
-func (r repository) FinishElimination(ctx context.Context, eliminationId string, participants []string) error {
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+// GetUnledgeredVote returns the first stored vote of an elimination that no
+// ledger entry holds, empty when every vote is in the ledger
+func (r repository) GetUnledgeredVote(ctx context.Context, eliminationId string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
 	defer cancel()
 
-	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
-		var query = `
-			UPDATE eliminations SET
-				open = false,
-				updated = now()
-			WHERE id = $1 AND open = true
-		`
+	var query = `
+		SELECT id FROM votes
+		WHERE elimination_id = $1
+		AND id NOT IN (SELECT unnest(vote_ids) FROM vote_ledger WHERE elimination_id = $1)
+		ORDER BY created, id
+		LIMIT 1
+	`
 
-		_, err := tx.ExecContext(ctx, query, eliminationId)
-		if err != nil {
-			return fmt.Errorf("failed to update elimination: %w", err)
+	var voteId string
+	err := r.db.GetContext(ctx, &voteId, query, eliminationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return "", nil
 		}
+		return "", fmt.Errorf("failed to get vote missing from the ledger: %w", err)
+	}
 
-		for _, participantId := range participants {
-			var query = `
-				UPDATE participants SET
-					elimination_id = null,
-					updated = now()
-				WHERE id = $1
-			`
+	return voteId, nil
+}
 
-			_, err := tx.ExecContext(ctx, query, participantId)
-			if err != nil {
-				return fmt.Errorf("failed to update participant: %w", err)
-			}
-		}
+// BackfillLedger chains the votes an elimination stored before the ledger existed
+func (r repository) BackfillLedger(ctx context.Context, eliminationId string) (int, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Minute)
+	defer cancel()
 
-		return nil
+	var chained int
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		var err error
+		chained, err = backfillLedger(ctx, tx, eliminationId)
+		return err
 	})
 	if err != nil {
-		return fmt.Errorf("failed to finish elimination: %w", err)
+		return 0, fmt.Errorf("failed to backfill ledger: %w", err)
 	}
 
-	return nil
+	return chained, nil
 }
 
-func (r repository) GetResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error) {
+// GetLedgerHead returns the last entry of the ledger of an elimination, an
+// elimination without votes has the genesis hash at entry 0
+func (r repository) GetLedgerHead(ctx context.Context, eliminationId string) (*LedgerEntry, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	var participants []ParticipantResult
+	return ledgerHead(ctx, r.db, eliminationId)
+}
+
+// GetParticipantIDs returns the IDs of the participants of an elimination,
+// without the removed ones
+func (r repository) GetParticipantIDs(ctx context.Context, eliminationId string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var participants []string
+	err := r.db.SelectContext(
+		ctx,
+		&participants,
+		"SELECT participant_id FROM elimination_participants WHERE elimination_id = $1 AND removed_at IS NULL",
+		eliminationId,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get elimination participants: %w", err)
+	}
+
+	return participants, nil
+}
+
+func (r repository) HasVerifiedVote(ctx context.Context, eliminationId, userId string) (bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
 
 	var query = `
-		SELECT
-			p.id AS "id",
-			p.name AS "name",
-			COUNT(v.id) AS "count"
-		FROM participants p
-		LEFT JOIN votes v ON p.id = v.participant_id AND v.elimination_id = $1
-		WHERE p.elimination_id = $1
-		GROUP BY p.id, p.name
-		ORDER BY COUNT(v.id) DESC
+		SELECT EXISTS (
+			SELECT 1
+			FROM votes
+			WHERE elimination_id = $1
+			AND user_id = $2
+			AND pool = 'verified'
+		)
 	`
 
-	err := r.db.SelectContext(ctx, &participants, query, eliminationId)
+	var exists bool
+	err := r.db.GetContext(ctx, &exists, query, eliminationId, userId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get votes: %w", err)
+		return false, fmt.Errorf("failed to check verified vote: %w", err)
 	}
 
-	return participants, nil
+	return exists, nil
 }
 
-func (r repository) InsertVote(ctx context.Context, vote Vote) error {
+// InsertVote stores a vote and appends it to the ledger, a vote whose ID was
+// already stored is ignored so a redelivered message can be processed more
+// than once, and a second verified vote of the same user in an elimination or
+// a vote of an elimination already drained is recorded as rejected
+// The vote is not counted here, the returned insertion is added to a Tally
+func (r repository) InsertVote(ctx context.Context, vote Vote) (*Insertion, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
@@ -168,196 +1347,220 @@ func (r repository) InsertVote(ctx context.Context, vote Vote) error {
 			user_id,
 			participant_id,
 			elimination_id,
+			origin,
+			pool,
 			created
 		) VALUES (
 			:id,
 			:user_id,
 			:participant_id,
 			:elimination_id,
+			:origin,
+			:pool,
 			:created
 		)
+		ON CONFLICT DO NOTHING
+		RETURNING *
 	`
 
-	_, err := r.db.NamedExecContext(ctx, query, vote)
+	var insertion = &Insertion{}
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		written, late, err := rejectDrained(ctx, tx, []Vote{vote})
+		if err != nil {
+			return err
+		}
+		if len(written) == 0 {
+			insertion = &Insertion{Rejected: late}
+			return nil
+		}
+
+		query, args, err := tx.BindNamed(query, vote)
+		if err != nil {
+			return fmt.Errorf("failed to bind vote: %w", err)
+		}
+
+		var inserted []Vote
+		err = tx.SelectContext(ctx, &inserted, query, args...)
+		if err != nil {
+			return fmt.Errorf("failed to insert vote: %w", err)
+		}
+
+		insertion, err = recordInserted(ctx, tx, written, inserted)
+		if err != nil {
+			return err
+		}
+		insertion.Rejected = append(insertion.Rejected, late...)
+		return nil
+	})
 	if err != nil {
-		return fmt.Errorf("failed to insert vote: %w", err)
+		return nil, fmt.Errorf("failed to insert vote: %w", err)
 	}
 
-	return nil
+	return insertion, nil
 }
 
-// TODO: Refactor the methods for fetching eliminations
-// use a boolean variable and use the same method
-// to fetch all eliminations or only the open eliminations
-
-func (r repository) GetAll(ctx context.Context) ([]EntityWithParticipants, error) {
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+// InsertVotes stores a batch of votes in a single transaction using COPY
+// The batch is copied into a staging table first, so votes whose ID was
+// already stored are ignored and conflicting verified votes are rejected just
+// like in InsertVote, as are the votes of an elimination already drained
+// The inserted votes are appended to the ledger in the same transaction
+func (r repository) InsertVotes(ctx context.Context, votes []Vote) (*Insertion, error) {
+	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
 	defer cancel()
 
-	var query = `
-		SELECT
-			e.id,
-			e.open,
-			e.start_date,
-			e.end_date,
-			e.created,
-			e.updated,
-			COALESCE(
-				json_agg(
-					json_build_object('id', p.id, 'name', p.name)
-				) FILTER (WHERE p.id IS NOT NULL)
-			) AS participants
-		FROM
-			eliminations e
-			LEFT JOIN participants p ON p.elimination_id = e.id
-		GROUP BY
-			e.id,
-			e.open,
-			e.start_date,
-			e.end_date,
-			e.created,
-			e.updated
-		ORDER BY e.created DESC
-	`
+	var insertion *Insertion
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		written, late, err := rejectDrained(ctx, tx, votes)
+		if err != nil {
+			return err
+		}
+		if len(written) == 0 {
+			insertion = &Insertion{Rejected: late}
+			return nil
+		}
 
-	var rows []struct {
-		ID           string    `db:"id"`
-		Open         bool      `db:"open"`
-		StartDate    time.Time `db:"start_date"`
-		EndDate      time.Time `db:"end_date"`
-		Created      time.Time `db:"created"`
-		Updated      time.Time `db:"updated"`
-		Participants []byte    `db:"participants"`
-	}
-	err := r.db.SelectContext(ctx, &rows, query)
-	if err != nil {
-		return nil, fmt.Errorf("failed to get eliminations: %w", err)
-	}
+		var query = `
+			CREATE TEMP TABLE votes_staging (
+				LIKE votes INCLUDING DEFAULTS
+			) ON COMMIT DROP
+		`
 
-	var eliminations []EntityWithParticipants
-	for _, r := range rows {
-		var participants = []Participant{}
+		_, err = tx.ExecContext(ctx, query)
+		if err != nil {
+			return fmt.Errorf("failed to create staging table: %w", err)
+		}
 
-		_ = json.Unmarshal(r.Participants, &participants)
+		stmt, err := tx.PrepareContext(
+			ctx,
+			pq.CopyIn(
+				"votes_staging",
+				"id",
+				"user_id",
+				"participant_id",
+				"elimination_id",
+				"origin",
+				"pool",
+				"created",
+			),
+		)
+		if err != nil {
+			return fmt.Errorf("failed to prepare copy: %w", err)
+		}
+		defer stmt.Close()
 
-		eliminations = append(eliminations, EntityWithParticipants{
-			Entity: Entity{
-				ID:        r.ID,
-				Open:      r.Open,
-				StartDate: r.StartDate,
-				EndDate:   r.EndDate,
-				Created:   r.Created,
-				Updated:   r.Updated,
-			},
-			Participants: participants,
-		})
+		for _, v := range written {
+			_, err := stmt.ExecContext(
+				ctx,
+				v.ID,
+				v.UserID,
+				v.ParticipantID,
+				v.EliminationID,
+				v.Origin,
+				v.Pool,
+				v.Created,
+			)
+			if err != nil {
+				return fmt.Errorf("failed to copy vote: %w", err)
+			}
+		}
+		if _, err := stmt.ExecContext(ctx); err != nil {
+			return fmt.Errorf("failed to flush copy: %w", err)
+		}
+
+		query = `
+			INSERT INTO votes (
+				id,
+				user_id,
+				participant_id,
+				elimination_id,
+				origin,
+				pool,
+				created
+			)
+			SELECT
+				id,
+				user_id,
+				participant_id,
+				elimination_id,
+				origin,
+				pool,
+				created
+			FROM votes_staging
+			ON CONFLICT DO NOTHING
+			RETURNING *
+		`
+
+		var inserted []Vote
+		err = tx.SelectContext(ctx, &inserted, query)
+		if err != nil {
+			return fmt.Errorf("failed to insert staged votes: %w", err)
+		}
+
+		// Only the votes actually inserted enter the ledger, redelivered ones are
+		// skipped and conflicting verified votes are rejected
+		insertion, err = recordInserted(ctx, tx, written, inserted)
+		if err != nil {
+			return err
+		}
+		insertion.Rejected = append(insertion.Rejected, late...)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to insert votes: %w", err)
 	}
 
-	return eliminations, nil
+	return insertion, nil
 }
 
-func (r repository) GetByIDWithParticipants(ctx context.Context, eliminationId string) (*EntityWithParticipants, error) {
-	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
-	defer cancel()
-
-	var query = `
-		SELECT
-			e.id,
-			e.open,
-			e.start_date,
-			e.end_date,
-			e.created,
-			e.updated,
-			COALESCE(
-				json_agg(
-					json_build_object('id', p.id, 'name', p.name)
-				) FILTER (WHERE p.id IS NOT NULL)
-			) AS participants
-		FROM
-			eliminations e
-			LEFT JOIN participants p ON p.elimination_id = e.id
-		WHERE e.id = $1
-		GROUP BY
-			e.id,
-			e.open,
-			e.start_date,
-			e.end_date,
-			e.created,
-			e.updated
-	`
+func (r repository) GetAll(ctx context.Context) ([]EntityWithParticipants, error) {
+	return r.selectWithParticipants(ctx, "")
+}
 
-	var row struct {
-		ID           string    `db:"id"`
-		Open         bool      `db:"open"`
-		StartDate    time.Time `db:"start_date"`
-		EndDate      time.Time `db:"end_date"`
-		Created      time.Time `db:"created"`
-		Updated      time.Time `db:"updated"`
-		Participants []byte    `db:"participants"`
-	}
+func (r repository) GetAllOpen(ctx context.Context) ([]EntityWithParticipants, error) {
+	return r.selectWithParticipants(ctx, "WHERE e.open = true")
+}
 
-	err := r.db.GetContext(ctx, &row, query, eliminationId)
+func (r repository) GetByIDWithParticipants(ctx context.Context, eliminationId string) (*EntityWithParticipants, error) {
+	eliminations, err := r.selectWithParticipants(ctx, "WHERE e.id = $1", eliminationId)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get eliminations: %w", err)
+		return nil, err
+	}
+	if len(eliminations) == 0 {
+		return nil, fmt.Errorf("failed to get eliminations: %w", sql.ErrNoRows)
 	}
 
-	var participants = []Participant{}
-	_ = json.Unmarshal(row.Participants, &participants)
-
-	return &EntityWithParticipants{
-		Entity: Entity{
-			ID:        row.ID,
-			Open:      row.Open,
-			StartDate: row.StartDate,
-			EndDate:   row.EndDate,
-			Created:   row.Created,
-			Updated:   row.Updated,
-		},
-		Participants: participants,
-	}, nil
+	return &eliminations[0], nil
 }
 
-func (r repository) GetAllOpen(ctx context.Context) ([]EntityWithParticipants, error) {
+// selectWithParticipants fetches the eliminations matching the given filter
+// along with their participants, newest first
+func (r repository) selectWithParticipants(ctx context.Context, filter string, args ...any) ([]EntityWithParticipants, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
-	var query = `
+	var query = fmt.Sprintf(`
 		SELECT
-			e.id,
-			e.open,
-			e.start_date,
-			e.end_date,
-			e.created,
-			e.updated,
+			e.*,
 			COALESCE(
 				json_agg(
 					json_build_object('id', p.id, 'name', p.name)
-				) FILTER (WHERE p.id IS NOT NULL)
+				) FILTER (WHERE p.id IS NOT NULL),
+				'[]'
 			) AS participants
 		FROM
 			eliminations e
-			LEFT JOIN participants p ON p.elimination_id = e.id
-		WHERE e.open = true
-		GROUP BY
-			e.id,
-			e.open,
-			e.start_date,
-			e.end_date,
-			e.created,
-			e.updated
+			LEFT JOIN elimination_participants ep ON ep.elimination_id = e.id AND ep.removed_at IS NULL
+			LEFT JOIN participants p ON p.id = ep.participant_id
+		%s
+		GROUP BY e.id
 		ORDER BY e.created DESC
-	`
+	`, filter)
 
 	var rows []struct {
-		ID           string    `db:"id"`
-		Open         bool      `db:"open"`
-		StartDate    time.Time `db:"start_date"`
-		EndDate      time.Time `db:"end_date"`
-		Created      time.Time `db:"created"`
-		Updated      time.Time `db:"updated"`
-		Participants []byte    `db:"participants"`
+		Entity
+		Participants []byte `db:"participants"`
 	}
-	err := r.db.SelectContext(ctx, &rows, query)
+	err := r.db.SelectContext(ctx, &rows, query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get eliminations: %w", err)
 	}
@@ -369,14 +1572,7 @@ func (r repository) GetAllOpen(ctx context.Context) ([]EntityWithParticipants, e
 		_ = json.Unmarshal(r.Participants, &participants)
 
 		eliminations = append(eliminations, EntityWithParticipants{
-			Entity: Entity{
-				ID:        r.ID,
-				Open:      r.Open,
-				StartDate: r.StartDate,
-				EndDate:   r.EndDate,
-				Created:   r.Created,
-				Updated:   r.Updated,
-			},
+			Entity:       r.Entity,
 			Participants: participants,
 		})
 	}
@@ -397,29 +1593,180 @@ func (r repository) GetByID(ctx context.Context, eliminationId string) (*Entity,
 	return &elimination, nil
 }
 
-func (r repository) Insert(ctx context.Context, elimination Entity) error {
+// errStatusChanged is returned when an elimination left the status a change was decided in
+var errStatusChanged = errors.New("elimination status changed")
+
+// SetTieBreakDecision stores the manual tie-break decision of an elimination
+// Only the decision is written, and only while the elimination is still in the
+// status it was read in, so a transition made by the scheduler in the meantime
+// is never reverted and errStatusChanged is returned instead
+func (r repository) SetTieBreakDecision(ctx context.Context, elimination Entity) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
 	var query = `
-		INSERT INTO eliminations (
-	    id,
-			open,
-			start_date,
-			end_date,
-			created,
-			updated
-		) VALUES (
-	    :id,
-			:open,
-			:start_date,
-			:end_date,
-			:created,
-			:updated
-		)
+		UPDATE eliminations SET
+			tie_break_decision = :tie_break_decision,
+			updated = :updated
+		WHERE id = :id AND status = :status
+	`
+
+	res, err := r.db.NamedExecContext(ctx, query, elimination)
+	if err != nil {
+		return fmt.Errorf("failed to update tie-break decision: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return errStatusChanged
+	}
+
+	return nil
+}
+
+// errParticipantNotFound is returned when a participant of a new elimination does not exist
+var errParticipantNotFound = errors.New("participant not found")
+
+// ineligibleParticipantError is returned when a participant cannot join a new elimination
+type ineligibleParticipantError struct {
+	name   string
+	status participant.Status
+}
+
+func (e ineligibleParticipantError) Error() string {
+	return fmt.Sprintf("participant %s is %s and cannot join an elimination", e.name, e.status)
+}
+
+// lockParticipants locks the participants of a new elimination, in ID order, and
+// checks they can join it, so none of them is withdrawn until it is stored
+func lockParticipants(ctx context.Context, tx *sqlx.Tx, participantIds []string) error {
+	var records []participant.Entity
+
+	var query = `
+		SELECT * FROM participants
+		WHERE id = ANY($1)
+		ORDER BY id
+		FOR UPDATE
 	`
 
-	_, err := r.db.NamedExecContext(ctx, query, elimination)
+	err := tx.SelectContext(ctx, &records, query, pq.Array(participantIds))
+	if err != nil {
+		return fmt.Errorf("failed to lock participants: %w", err)
+	}
+
+	var found = make(map[string]bool, len(records))
+	for _, record := range records {
+		found[record.ID] = true
+
+		p, err := participant.NewParticipantFromDatabase(record)
+		if err != nil {
+			return fmt.Errorf("failed to create participant from database: %w", err)
+		}
+		if !p.CanJoinElimination() {
+			return ineligibleParticipantError{name: p.Name(), status: p.Status()}
+		}
+	}
+	for _, id := range participantIds {
+		if !found[id] {
+			return fmt.Errorf("%w: %s", errParticipantNotFound, id)
+		}
+	}
+
+	return nil
+}
+
+// Insert stores a new elimination along with its participants
+// The participants are checked and, for an elimination poll, put up for
+// elimination in the same transaction
+func (r repository) Insert(ctx context.Context, elimination Entity, participants []string) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	err := util.ExecTx(ctx, r.db, func(tx *sqlx.Tx) error {
+		err := lockParticipants(ctx, tx, participants)
+		if err != nil {
+			return err
+		}
+
+		var query = `
+			INSERT INTO eliminations (
+				id,
+				open,
+				type,
+				vote_mode,
+				eliminated_count,
+				status,
+				start_date,
+				end_date,
+				tie_break_policy,
+				tie_break_priority,
+				tie_break_decision,
+				voting_scheme,
+				verified_weight,
+				fan_weight,
+				created,
+				updated
+			) VALUES (
+				:id,
+				:open,
+				:type,
+				:vote_mode,
+				:eliminated_count,
+				:status,
+				:start_date,
+				:end_date,
+				:tie_break_policy,
+				:tie_break_priority,
+				:tie_break_decision,
+				:voting_scheme,
+				:verified_weight,
+				:fan_weight,
+				:created,
+				:updated
+			)
+		`
+
+		_, err = tx.NamedExecContext(ctx, query, elimination)
+		if err != nil {
+			return fmt.Errorf("failed to insert elimination: %w", err)
+		}
+
+		for _, participantId := range participants {
+			var query = `
+				INSERT INTO elimination_participants (
+					elimination_id,
+					participant_id
+				) VALUES ($1, $2)
+			`
+
+			_, err := tx.ExecContext(ctx, query, elimination.ID, participantId)
+			if err != nil {
+				return fmt.Errorf("failed to insert elimination participant: %w", err)
+			}
+		}
+
+		// Only an elimination poll puts its participants up for elimination, they
+		// can be in any number of polls at once
+		if elimination.Type != PollElimination {
+			return nil
+		}
+
+		query = `
+			UPDATE participants SET
+				status = 'in_elimination',
+				updated = now()
+			WHERE id = ANY($1)
+		`
+
+		_, err = tx.ExecContext(ctx, query, pq.Array(participants))
+		if err != nil {
+			return fmt.Errorf("failed to put participants up for elimination: %w", err)
+		}
+
+		return nil
+	})
 	if err != nil {
 		return fmt.Errorf("failed to insert elimination: %w", err)
 	}
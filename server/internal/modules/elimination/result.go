@@ -0,0 +1,327 @@
+package elimination
+
+import (
+	"context"
+	"errors"
+	"math/big"
+	"slices"
+	"strings"
+)
+
+// errTieUndecided is returned when a tie needs a manual decision that was not made yet
+var errTieUndecided = errors.New("elimination is tied and requires a manual tie-break decision")
+
+// decision splits the participants an elimination decides on into the ones
+// decided outright and the ones tied for the seats left
+// Participants are taken by score, the most voted first or the fewest voted
+// first when voting to save, until the eliminated count is reached
+// Nobody is decided when nobody received votes
+func decision(elimination Entity, results []ParticipantResult) (decided, tied []int, seats int) {
+	var voted bool
+	var order = make([]int, len(results))
+	for i, r := range results {
+		order[i] = i
+		voted = voted || r.score.Sign() > 0
+	}
+	if !voted {
+		return nil, nil, 0
+	}
+
+	slices.SortStableFunc(order, func(a, b int) int {
+		if elimination.VoteMode == VoteToSave {
+			return results[a].score.Cmp(results[b].score)
+		}
+		return results[b].score.Cmp(results[a].score)
+	})
+
+	seats = max(elimination.EliminatedCount, 1)
+	for i := 0; i < len(order) && seats > 0; {
+		j := i + 1
+		for j < len(order) && results[order[j]].score.Cmp(results[order[i]].score) == 0 {
+			j++
+		}
+
+		group := order[i:j]
+		if len(group) > seats {
+			return decided, group, seats
+		}
+		decided = append(decided, group...)
+		seats -= len(group)
+		i = j
+	}
+
+	return decided, nil, 0
+}
+
+// buildResult fills the totals, percentages and ties of a raw result and
+// ranks the participants according to the elimination voting scheme
+func buildResult(elimination Entity, results []ParticipantResult) []ParticipantResult {
+	var total, verifiedTotal, fanTotal int
+	for _, r := range results {
+		total += r.Count
+		verifiedTotal += r.VerifiedCount
+		fanTotal += r.FanCount
+	}
+
+	for i := range results {
+		r := &results[i]
+		r.TotalVotes = total
+		r.VoteMode = elimination.VoteMode
+
+		if elimination.VotingScheme != VotingSchemeDualPool {
+			r.score = big.NewInt(int64(r.Count))
+			continue
+		}
+
+		// The weighted share w_v * verified/verifiedTotal + w_f * fan/fanTotal is
+		// scaled by both totals so it can be compared exactly as an integer
+		// A pool without votes leaves the whole result to the other pool
+		switch {
+		case verifiedTotal > 0 && fanTotal > 0:
+			verifiedShare := product(elimination.VerifiedWeight, r.VerifiedCount, fanTotal)
+			fanShare := product(elimination.FanWeight, r.FanCount, verifiedTotal)
+			r.score = verifiedShare.Add(verifiedShare, fanShare)
+		case verifiedTotal > 0:
+			r.score = big.NewInt(int64(r.VerifiedCount))
+		default:
+			r.score = big.NewInt(int64(r.FanCount))
+		}
+	}
+
+	var scores = make([]*big.Int, len(results))
+	var verified = make([]*big.Int, len(results))
+	var fan = make([]*big.Int, len(results))
+	for i, r := range results {
+		scores[i] = r.score
+		verified[i] = big.NewInt(int64(r.VerifiedCount))
+		fan[i] = big.NewInt(int64(r.FanCount))
+	}
+
+	percentages := largestRemainder(scores)
+	verifiedPercentages := largestRemainder(verified)
+	fanPercentages := largestRemainder(fan)
+	for i := range results {
+		results[i].Percentage = percentages[i]
+		if elimination.VotingScheme == VotingSchemeDualPool {
+			results[i].Pools = map[Pool]PoolResult{
+				PoolVerified: {Count: results[i].VerifiedCount, Percentage: verifiedPercentages[i]},
+				PoolFan:      {Count: results[i].FanCount, Percentage: fanPercentages[i]},
+			}
+		}
+	}
+
+	slices.SortStableFunc(results, func(a, b ParticipantResult) int {
+		return b.score.Cmp(a.score)
+	})
+
+	// A tie is only reported between the participants competing for the last seats
+	_, tied, _ := decision(elimination, results)
+	for _, i := range tied {
+		results[i].Tied = true
+	}
+
+	return results
+}
+
+// computeOutcomes turns the final result of an elimination into its outcome
+// The participants with the most votes, or the fewest when voting to save, are
+// decided according to the poll type up to the eliminated count, a tie for the
+// last seats is settled by the elimination tie-break policy, and nobody is
+// decided when the elimination received no votes
+func computeOutcomes(elimination Entity, results []ParticipantResult) ([]Outcome, error) {
+	results = buildResult(elimination, results)
+
+	var outcomes = make([]Outcome, 0, len(results))
+	for _, r := range results {
+		outcomes = append(outcomes, Outcome{
+			EliminationID: elimination.ID,
+			ParticipantID: r.ID,
+			Name:          r.Name,
+			Votes:         r.Count,
+			Percentage:    r.Percentage,
+		})
+	}
+
+	decided, tiedIndexes, seats := decision(elimination, results)
+	for _, i := range decided {
+		decide(elimination.Type, &outcomes[i])
+	}
+
+	tied := make([]ParticipantResult, 0, len(tiedIndexes))
+	for _, i := range tiedIndexes {
+		tied = append(tied, results[i])
+		outcomes[i].Tied = true
+	}
+
+	// Each seat left goes to the participant the policy picks among the ones
+	// still tied
+	for ; seats > 0; seats-- {
+		decidedId, err := breakTie(elimination, tied)
+		if err != nil {
+			return nil, err
+		}
+		for i := range outcomes {
+			if outcomes[i].ParticipantID == decidedId {
+				decide(elimination.Type, &outcomes[i])
+			}
+		}
+		tied = slices.DeleteFunc(tied, func(r ParticipantResult) bool { return r.ID == decidedId })
+	}
+
+	return outcomes, nil
+}
+
+// decide applies the result of a poll to a participant it decided on
+func decide(pollType PollType, outcome *Outcome) {
+	if pollType == PollElimination {
+		outcome.Eliminated = true
+		return
+	}
+	outcome.Winner = true
+}
+
+// breakTie returns the tied participant decided by the elimination tie-break policy
+func breakTie(elimination Entity, tied []ParticipantResult) (string, error) {
+	switch elimination.TieBreakPolicy {
+	case TieBreakPriority:
+		for _, participantId := range elimination.TieBreakPriority {
+			if slices.ContainsFunc(tied, func(r ParticipantResult) bool { return r.ID == participantId }) {
+				return participantId, nil
+			}
+		}
+		return "", errors.New("no tied participant is listed in the tie-break priority")
+
+	case TieBreakManual:
+		decision := elimination.TieBreakDecision
+		if decision == nil {
+			return "", errTieUndecided
+		}
+		if !slices.ContainsFunc(tied, func(r ParticipantResult) bool { return r.ID == *decision }) {
+			return "", errors.New("tie-break decision is not one of the tied participants")
+		}
+		return *decision, nil
+
+	default:
+		// The participant who reached the tied count first is decided, or the
+		// one who reached it last when voting to save, as they were saved last
+		return reachedFirst(tied, elimination.VoteMode == VoteToSave).ID, nil
+	}
+}
+
+// reachedFirst returns the tied participant who reached the tied count first,
+// or last when latest is set
+// A participant without votes never reached it, so they come after everyone
+// who did, and participants reaching it at the same time are ordered by ID so
+// the choice never depends on the order of the results
+func reachedFirst(tied []ParticipantResult, latest bool) ParticipantResult {
+	return slices.MinFunc(tied, func(a, b ParticipantResult) int {
+		var c int
+		switch {
+		case a.ReachedAt == nil && b.ReachedAt == nil:
+		case a.ReachedAt == nil:
+			c = 1
+		case b.ReachedAt == nil:
+			c = -1
+		default:
+			c = a.ReachedAt.Compare(*b.ReachedAt)
+		}
+		if latest {
+			c = -c
+		}
+		if c != 0 {
+			return c
+		}
+		return strings.Compare(a.ID, b.ID)
+	})
+}
+
+// largestRemainder converts vote scores into percentages with two decimals
+// that always add up to exactly 100.00, using the largest remainder method
+// Every percentage is first rounded down to the hundredth, and the hundredths
+// still missing go to the scores with the largest remainders
+// Every percentage is zero when there are no votes
+func largestRemainder(scores []*big.Int) []float64 {
+	var scale = big.NewInt(100 * 100) // hundredths of a percent
+
+	var total = new(big.Int)
+	for _, s := range scores {
+		total.Add(total, s)
+	}
+
+	var percentages = make([]float64, len(scores))
+	if total.Sign() == 0 {
+		return percentages
+	}
+
+	var units = make([]int64, len(scores))
+	var remainders = make([]*big.Int, len(scores))
+	var allocated int64
+	for i, s := range scores {
+		scaled := new(big.Int).Mul(s, scale)
+		unit, remainder := new(big.Int).QuoRem(scaled, total, new(big.Int))
+		units[i] = unit.Int64()
+		remainders[i] = remainder
+		allocated += units[i]
+	}
+
+	// Equal remainders favor the larger score, and then the original order
+	var order = make([]int, len(scores))
+	for i := range order {
+		order[i] = i
+	}
+	slices.SortStableFunc(order, func(a, b int) int {
+		if cmp := remainders[b].Cmp(remainders[a]); cmp != 0 {
+			return cmp
+		}
+		return scores[b].Cmp(scores[a])
+	})
+	for _, i := range order[:scale.Int64()-allocated] {
+		units[i]++
+	}
+
+	for i, u := range units {
+		percentages[i] = float64(u) / 100
+	}
+
+	return percentages
+}
+
+// product multiplies the given numbers without overflowing
+func product(values ...int) *big.Int {
+	var res = big.NewInt(1)
+	for _, v := range values {
+		res.Mul(res, big.NewInt(int64(v)))
+	}
+	return res
+}
+
+// currentResult returns the result of an elimination as every reader must see
+// it, the snapshot frozen when it was finished or else its live result
+// A fresh live result is counted from the stored votes instead of the counters
+func currentResult(ctx context.Context, eliminationRepo Repository, elimination Entity, fresh bool) ([]ParticipantResult, error) {
+	if elimination.Status == StatusClosed {
+		snapshot, err := eliminationRepo.GetSnapshot(ctx, elimination.ID)
+		if err != nil {
+			return nil, err
+		}
+		if snapshot != nil {
+			// Snapshots frozen before vote modes existed do not carry the mode
+			for i := range snapshot {
+				snapshot[i].VoteMode = elimination.VoteMode
+			}
+			return snapshot, nil
+		}
+	}
+
+	getResult := eliminationRepo.GetResult
+	if fresh {
+		getResult = eliminationRepo.GetFreshResult
+	}
+
+	results, err := getResult(ctx, elimination.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return buildResult(elimination, results), nil
+}
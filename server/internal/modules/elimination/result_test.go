@@ -0,0 +1,323 @@
+package elimination
+
+import (
+	"math"
+	"math/big"
+	"slices"
+	"testing"
+	"time"
+)
+
+func TestComputeOutcomesTieWithoutVotes(t *testing.T) {
+	var base = time.Date(2025, 4, 18, 20, 0, 0, 0, time.UTC)
+	at := func(seconds int) *time.Time {
+		reached := base.Add(time.Duration(seconds) * time.Second)
+		return &reached
+	}
+
+	tests := []struct {
+		name            string
+		mode            VoteMode
+		eliminatedCount int
+		results         []ParticipantResult
+		want            []string
+	}{
+		{
+			name:            "one seat left between participants without votes",
+			mode:            VoteToEliminate,
+			eliminatedCount: 2,
+			results: []ParticipantResult{
+				{ID: "c", Count: 0},
+				{ID: "a", Count: 10, ReachedAt: at(5)},
+				{ID: "b", Count: 0},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name:            "two seats between participants without votes",
+			mode:            VoteToEliminate,
+			eliminatedCount: 3,
+			results: []ParticipantResult{
+				{ID: "d", Count: 0},
+				{ID: "a", Count: 10, ReachedAt: at(5)},
+				{ID: "c", Count: 0},
+				{ID: "b", Count: 0},
+			},
+			want: []string{"a", "b", "c"},
+		},
+		{
+			name:            "participants with votes are tied with each other only",
+			mode:            VoteToEliminate,
+			eliminatedCount: 2,
+			results: []ParticipantResult{
+				{ID: "a", Count: 10, ReachedAt: at(1)},
+				{ID: "b", Count: 4, ReachedAt: at(9)},
+				{ID: "c", Count: 4, ReachedAt: at(3)},
+				{ID: "d", Count: 0},
+			},
+			want: []string{"a", "c"},
+		},
+		{
+			name:            "same reach time falls back to ID",
+			mode:            VoteToEliminate,
+			eliminatedCount: 2,
+			results: []ParticipantResult{
+				{ID: "a", Count: 10, ReachedAt: at(1)},
+				{ID: "c", Count: 4, ReachedAt: at(3)},
+				{ID: "b", Count: 4, ReachedAt: at(3)},
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name:            "save mode decides on participants without votes first",
+			mode:            VoteToSave,
+			eliminatedCount: 1,
+			results: []ParticipantResult{
+				{ID: "a", Count: 10, ReachedAt: at(1)},
+				{ID: "c", Count: 0},
+				{ID: "b", Count: 0},
+			},
+			want: []string{"b"},
+		},
+		{
+			name:            "save mode with a seat left among voted participants",
+			mode:            VoteToSave,
+			eliminatedCount: 2,
+			results: []ParticipantResult{
+				{ID: "a", Count: 0},
+				{ID: "b", Count: 3, ReachedAt: at(2)},
+				{ID: "c", Count: 3, ReachedAt: at(7)},
+				{ID: "d", Count: 9, ReachedAt: at(1)},
+			},
+			want: []string{"a", "c"},
+		},
+		{
+			name:            "nobody decided without votes",
+			mode:            VoteToEliminate,
+			eliminatedCount: 2,
+			results: []ParticipantResult{
+				{ID: "a", Count: 0},
+				{ID: "b", Count: 0},
+				{ID: "c", Count: 0},
+			},
+			want: nil,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elimination := Entity{
+				ID:              "elimination",
+				Type:            PollElimination,
+				VoteMode:        tt.mode,
+				EliminatedCount: tt.eliminatedCount,
+				TieBreakPolicy:  TieBreakEarliestVote,
+			}
+
+			outcomes, err := computeOutcomes(elimination, slices.Clone(tt.results))
+			if err != nil {
+				t.Fatalf("computeOutcomes() error = %v", err)
+			}
+
+			var got []string
+			for _, o := range outcomes {
+				if o.Eliminated {
+					got = append(got, o.ParticipantID)
+				}
+			}
+			slices.Sort(got)
+
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("eliminated = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLargestRemainder(t *testing.T) {
+	tests := []struct {
+		name   string
+		scores []int64
+		want   []float64
+	}{
+		{
+			name:   "no votes",
+			scores: []int64{0, 0, 0},
+			want:   []float64{0, 0, 0},
+		},
+		{
+			name:   "single participant",
+			scores: []int64{7},
+			want:   []float64{100},
+		},
+		{
+			name:   "single participant among participants without votes",
+			scores: []int64{0, 3, 0},
+			want:   []float64{0, 100, 0},
+		},
+		{
+			name:   "exact split",
+			scores: []int64{1, 3},
+			want:   []float64{25, 75},
+		},
+		{
+			name:   "missing hundredth goes to the largest remainder",
+			scores: []int64{2, 1},
+			want:   []float64{66.67, 33.33},
+		},
+		{
+			name:   "equal remainders and scores keep the original order",
+			scores: []int64{1, 1, 1},
+			want:   []float64{33.34, 33.33, 33.33},
+		},
+		{
+			name:   "equal remainders favor the larger score",
+			scores: []int64{1, 4, 19},
+			want:   []float64{4.16, 16.67, 79.17},
+		},
+		{
+			name:   "many small scores",
+			scores: []int64{1, 1, 1, 1, 1, 1, 1},
+			want:   []float64{14.29, 14.29, 14.29, 14.29, 14.28, 14.28, 14.28},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var scores = make([]*big.Int, len(tt.scores))
+			var total int64
+			for i, s := range tt.scores {
+				scores[i] = big.NewInt(s)
+				total += s
+			}
+
+			got := largestRemainder(scores)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("largestRemainder() = %v, want %v", got, tt.want)
+			}
+
+			// Summed in hundredths, so float rounding cannot hide a missing one
+			var hundredths int64
+			for _, p := range got {
+				hundredths += int64(math.Round(p * 100))
+			}
+			if total > 0 && hundredths != 100*100 {
+				t.Errorf("percentages add up to %.2f, want 100.00", float64(hundredths)/100)
+			}
+		})
+	}
+}
+
+func TestBuildResultDualPool(t *testing.T) {
+	type want struct {
+		id         string
+		percentage float64
+		verified   float64
+		fan        float64
+	}
+
+	tests := []struct {
+		name    string
+		results []ParticipantResult
+		want    []want
+	}{
+		{
+			name: "both pools weighted 70/30",
+			results: []ParticipantResult{
+				{ID: "b", VerifiedCount: 1, FanCount: 3},
+				{ID: "a", VerifiedCount: 3, FanCount: 1},
+			},
+			want: []want{
+				{id: "a", percentage: 60, verified: 75, fan: 25},
+				{id: "b", percentage: 40, verified: 25, fan: 75},
+			},
+		},
+		{
+			name: "weighted shares are rounded to 100",
+			results: []ParticipantResult{
+				{ID: "a", VerifiedCount: 1, FanCount: 1},
+				{ID: "b", VerifiedCount: 1},
+				{ID: "c", VerifiedCount: 1},
+			},
+			want: []want{
+				{id: "a", percentage: 53.34, verified: 33.34, fan: 100},
+				{id: "b", percentage: 23.33, verified: 33.33, fan: 0},
+				{id: "c", percentage: 23.33, verified: 33.33, fan: 0},
+			},
+		},
+		{
+			name: "fan votes outweigh a verified lead",
+			results: []ParticipantResult{
+				{ID: "a", VerifiedCount: 2, FanCount: 0},
+				{ID: "b", VerifiedCount: 1, FanCount: 10},
+			},
+			want: []want{
+				// b: 0.7 * 33.3 + 0.3 * 100, a: 0.7 * 66.7
+				{id: "b", percentage: 53.33, verified: 33.33, fan: 100},
+				{id: "a", percentage: 46.67, verified: 66.67, fan: 0},
+			},
+		},
+		{
+			name: "empty fan pool leaves the result to the verified pool",
+			results: []ParticipantResult{
+				{ID: "a", VerifiedCount: 1},
+				{ID: "b", VerifiedCount: 3},
+			},
+			want: []want{
+				{id: "b", percentage: 75, verified: 75, fan: 0},
+				{id: "a", percentage: 25, verified: 25, fan: 0},
+			},
+		},
+		{
+			name: "empty verified pool leaves the result to the fan pool",
+			results: []ParticipantResult{
+				{ID: "a", FanCount: 9},
+				{ID: "b", FanCount: 1},
+			},
+			want: []want{
+				{id: "a", percentage: 90, verified: 0, fan: 90},
+				{id: "b", percentage: 10, verified: 0, fan: 10},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elimination := Entity{
+				ID:              "elimination",
+				Type:            PollElimination,
+				VoteMode:        VoteToEliminate,
+				VotingScheme:    VotingSchemeDualPool,
+				VerifiedWeight:  70,
+				FanWeight:       30,
+				EliminatedCount: 1,
+			}
+
+			var results = slices.Clone(tt.results)
+			for i := range results {
+				results[i].Count = results[i].VerifiedCount + results[i].FanCount
+			}
+
+			got := buildResult(elimination, results)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %d results, want %d", len(got), len(tt.want))
+			}
+			for i, w := range tt.want {
+				r := got[i]
+				if r.ID != w.id {
+					t.Errorf("result %d is %s, want %s", i, r.ID, w.id)
+					continue
+				}
+				if r.Percentage != w.percentage {
+					t.Errorf("%s percentage = %v, want %v", r.ID, r.Percentage, w.percentage)
+				}
+				if p := r.Pools[PoolVerified].Percentage; p != w.verified {
+					t.Errorf("%s verified percentage = %v, want %v", r.ID, p, w.verified)
+				}
+				if p := r.Pools[PoolFan].Percentage; p != w.fan {
+					t.Errorf("%s fan percentage = %v, want %v", r.ID, p, w.fan)
+				}
+			}
+		})
+	}
+}
@@ -1,9 +1,16 @@
 package elimination
 
 import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
 	"sync"
+	"time"
 
+	"github.com/bernardinorafael/globo-challenge/internal/infra/challenge"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/client"
 	"github.com/bernardinorafael/globo-challenge/internal/infra/http/middleware"
 	"github.com/bernardinorafael/globo-challenge/internal/infra/token"
 	"github.com/bernardinorafael/globo-challenge/internal/shared/dto"
@@ -12,6 +19,12 @@ import (
 	"github.com/go-chi/chi"
 )
 
+const (
+	// streamKeepAlive is how often an idle result stream sends a comment line
+	// so proxies do not close the connection
+	streamKeepAlive = time.Second * 15
+)
+
 var (
 	instance *controller
 	Once     sync.Once
@@ -19,13 +32,15 @@ var (
 
 type controller struct {
 	eliminationService Service
+	challenges         *challenge.Issuer
 	secretKey          string
 }
 
-func NewController(eliminationService Service, secretKey string) *controller {
+func NewController(eliminationService Service, challenges *challenge.Issuer, secretKey string) *controller {
 	Once.Do(func() {
 		instance = &controller{
 			eliminationService: eliminationService,
+			challenges:         challenges,
 			secretKey:          secretKey,
 		}
 	})
@@ -38,20 +53,36 @@ func (c controller) RegisterRoutes(r *chi.Mux) {
 	r.Route("/api/v1/eliminations", func(r chi.Router) {
 		// Private
 		r.With(m.WithAuth).Post("/", c.handleCreateElimination)
+		r.With(m.WithAuth).Get("/{eliminationId}/challenge", c.handleGetChallenge)
 		r.With(m.WithAuth).Post("/{eliminationId}/vote", c.handleVote)
 		r.With(m.WithAuth).Get("/{eliminationId}/result", c.handleGetResult)
+		r.With(m.WithAuth).Get("/{eliminationId}/result/stream", c.handleStreamResult)
 		r.With(m.WithAuth).Patch("/{eliminationId}/finish", c.handleFinishElimination)
+		r.With(m.WithAuth, m.WithAdmin).Patch("/{eliminationId}/tie-break", c.handleDecideTie)
+		r.With(m.WithAuth).Get("/{eliminationId}/outcome", c.handleGetOutcomes)
+		r.With(m.WithAuth, m.WithAdmin).Patch("/{eliminationId}/remove-participant", c.handleRemoveParticipant)
+		r.With(m.WithAuth, m.WithAdmin).Get("/{eliminationId}/events", c.handleGetEvents)
 		r.With(m.WithAuth).Get("/", c.handleGetAllEliminations)
-		r.With(m.WithAuth).Get("/dashboard", c.handleGetDashboard)
+		r.With(m.WithAuth).Get("/overview", c.handleGetOverview)
+		r.With(m.WithAuth).Get("/{eliminationId}/dashboard", c.handleGetDashboard)
 		// Public
 		r.Get("/open", c.handleGetAllEliminationsOpen)
+		r.Get("/{eliminationId}/certificate", c.handleGetCertificate)
+	})
+
+	r.Route("/api/v1/votes", func(r chi.Router) {
+		// Private
+		r.With(m.WithAuth).Get("/{voteId}", c.handleGetVoteStatus)
+		r.With(m.WithAuth).Get("/{voteId}/proof", c.handleGetVoteProof)
+		// Public
+		r.Post("/verify", c.handleVerifyReceipt)
 	})
 }
 
 func (c controller) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	res, err := c.eliminationService.GetDashboard(ctx)
+	res, err := c.eliminationService.GetDashboard(ctx, chi.URLParam(r, "eliminationId"), dashboardQuery(r))
 	if err != nil {
 		errs.HttpError(w, err)
 		return
@@ -60,10 +91,36 @@ func (c controller) handleGetDashboard(w http.ResponseWriter, r *http.Request) {
 	util.WriteJSON(w, http.StatusOK, res)
 }
 
+func (c controller) handleGetOverview(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := c.eliminationService.GetOverview(ctx)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+// dashboardQuery reads the bucket and tz query parameters of a dashboard request
+func dashboardQuery(r *http.Request) dto.DashboardQuery {
+	return dto.DashboardQuery{
+		Bucket:   r.URL.Query().Get("bucket"),
+		Timezone: r.URL.Query().Get("tz"),
+	}
+}
+
 func (c controller) handleGetResult(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	res, err := c.eliminationService.GetResult(ctx, chi.URLParam(r, "eliminationId"))
+	// fresh=true counts the stored votes instead of reading the flushed counters,
+	// it is only honoured for admins checking the live count since every call
+	// scans the votes of the elimination
+	claims, _ := ctx.Value(middleware.AuthKey{}).(*token.Claims)
+	fresh := claims != nil && claims.IsAdmin() && r.URL.Query().Get("fresh") == "true"
+
+	res, err := c.eliminationService.GetResult(ctx, chi.URLParam(r, "eliminationId"), fresh)
 	if err != nil {
 		errs.HttpError(w, err)
 		return
@@ -72,6 +129,72 @@ func (c controller) handleGetResult(w http.ResponseWriter, r *http.Request) {
 	util.WriteJSON(w, http.StatusOK, res)
 }
 
+func (c controller) handleStreamResult(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		errs.HttpError(w, errs.NewBadRequestError("streaming is not supported", nil))
+		return
+	}
+
+	current, updates, unsubscribe, err := c.eliminationService.SubscribeResult(ctx, chi.URLParam(r, "eliminationId"))
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+	defer unsubscribe()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+
+	// A reconnecting client that already holds the current result only waits for updates
+	lastEventId := r.Header.Get("Last-Event-ID")
+	if current.ID != lastEventId {
+		if err := writeEvent(w, "result", current.ID, current.Results); err != nil {
+			return
+		}
+		lastEventId = current.ID
+	}
+	flusher.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(w, ": keep-alive\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event := <-updates:
+			if event.ID == lastEventId {
+				continue
+			}
+			if err := writeEvent(w, "result", event.ID, event.Results); err != nil {
+				return
+			}
+			lastEventId = event.ID
+			flusher.Flush()
+		}
+	}
+}
+
+// writeEvent writes a server-sent event with a JSON payload
+func writeEvent(w http.ResponseWriter, name, id string, data any) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", id, name, payload)
+	return err
+}
+
 func (c controller) handleFinishElimination(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -84,6 +207,148 @@ func (c controller) handleFinishElimination(w http.ResponseWriter, r *http.Reque
 	util.WriteSuccess(w, http.StatusOK)
 }
 
+func (c controller) handleDecideTie(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body dto.DecideTie
+	err := util.ReadRequestBody(w, r, &body)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	err = c.eliminationService.DecideTie(ctx, chi.URLParam(r, "eliminationId"), body)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteSuccess(w, http.StatusOK)
+}
+
+func (c controller) handleGetOutcomes(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := c.eliminationService.GetOutcomes(ctx, chi.URLParam(r, "eliminationId"))
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (c controller) handleRemoveParticipant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := ctx.Value(middleware.AuthKey{}).(*token.Claims)
+	if !ok {
+		errs.HttpError(w, errs.NewUnauthorizedError("invalid and/or expired token", nil))
+		return
+	}
+
+	var body dto.RemoveParticipant
+	err := util.ReadRequestBody(w, r, &body)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+	body.ActorID = claims.UserID
+
+	res, err := c.eliminationService.RemoveParticipant(ctx, chi.URLParam(r, "eliminationId"), body)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (c controller) handleGetEvents(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := c.eliminationService.GetEvents(ctx, chi.URLParam(r, "eliminationId"))
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (c controller) handleGetVoteStatus(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := ctx.Value(middleware.AuthKey{}).(*token.Claims)
+	if !ok {
+		errs.HttpError(w, errs.NewUnauthorizedError("invalid and/or expired token", nil))
+		return
+	}
+
+	res, err := c.eliminationService.GetVoteStatus(ctx, chi.URLParam(r, "voteId"), claims.UserID)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (c controller) handleVerifyReceipt(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	var body Receipt
+	err := util.ReadRequestBody(w, r, &body)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, c.eliminationService.VerifyReceipt(ctx, body))
+}
+
+func (c controller) handleGetCertificate(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := c.eliminationService.GetCertificate(ctx, chi.URLParam(r, "eliminationId"))
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (c controller) handleGetVoteProof(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	res, err := c.eliminationService.GetVoteProof(ctx, chi.URLParam(r, "voteId"))
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
+func (c controller) handleGetChallenge(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	claims, ok := ctx.Value(middleware.AuthKey{}).(*token.Claims)
+	if !ok {
+		errs.HttpError(w, errs.NewUnauthorizedError("invalid and/or expired token", nil))
+		return
+	}
+
+	res, err := c.challenges.Issue(claims.UserID, chi.URLParam(r, "eliminationId"), remoteIP(r))
+	if err != nil {
+		errs.HttpError(w, errs.NewInternalServerError(err))
+		return
+	}
+
+	util.WriteJSON(w, http.StatusOK, res)
+}
+
 func (c controller) handleVote(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
@@ -94,38 +359,56 @@ func (c controller) handleVote(w http.ResponseWriter, r *http.Request) {
 	}
 
 	var body dto.CreateVote
+	err := util.ReadRequestBody(w, r, &body)
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	// The identity of the voter is set after decoding so the body cannot override it
 	body.EliminationID = chi.URLParam(r, "eliminationId")
 	body.UserID = claims.UserID
 
-	err := util.ReadRequestBody(w, r, &body)
+	err = c.challenges.Verify(
+		ctx,
+		body.Challenge,
+		body.Solution,
+		claims.UserID,
+		body.EliminationID,
+	)
 	if err != nil {
-		errs.HttpError(w, err)
+		errs.HttpError(w, challengeError(err))
 		return
 	}
 
-	err = c.eliminationService.Vote(ctx, body)
+	body.IP = remoteIP(r)
+	body.Verified = claims.Verified
+	// Tokens issued before channels existed were all issued to the web client
+	body.Origin = claims.Channel
+	if body.Origin == "" {
+		body.Origin = string(client.DefaultChannel)
+	}
+
+	receipt, err := c.eliminationService.Vote(ctx, body)
 	if err != nil {
 		errs.HttpError(w, err)
 		return
 	}
 
-	util.WriteSuccess(w, http.StatusCreated)
+	util.WriteJSON(w, http.StatusCreated, receipt)
 }
 
 func (c controller) handleCreateElimination(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	var body struct {
-		Participants []string `json:"participants"`
-	}
-
+	var body dto.CreateElimination
 	err := util.ReadRequestBody(w, r, &body)
 	if err != nil {
 		errs.HttpError(w, err)
 		return
 	}
 
-	err = c.eliminationService.CreateElimination(ctx, body.Participants)
+	err = c.eliminationService.CreateElimination(ctx, body)
 	if err != nil {
 		errs.HttpError(w, err)
 		return
@@ -157,3 +440,26 @@ func (c controller) handleGetAllEliminations(w http.ResponseWriter, r *http.Requ
 
 	util.WriteJSON(w, http.StatusOK, eliminations)
 }
+
+// challengeError maps a failed challenge verification to an application error
+func challengeError(err error) error {
+	switch {
+	case errors.Is(err, challenge.ErrUsed):
+		return errs.NewConflictError("challenge was already used", err)
+	case errors.Is(err, challenge.ErrExpired):
+		return errs.NewForbiddenError("challenge has expired", errs.Expired, err)
+	case errors.Is(err, challenge.ErrInvalid), errors.Is(err, challenge.ErrUnsolved):
+		return errs.NewForbiddenError("a solved vote challenge is required", errs.InvalidChallenge, err)
+	default:
+		return errs.NewInternalServerError(err)
+	}
+}
+
+// remoteIP returns the IP of the client without the port
+func remoteIP(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
@@ -0,0 +1,117 @@
+package elimination
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/util"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	// schedulerLockKey identifies the advisory lock that elects the replica running the scheduler
+	schedulerLockKey int64 = 20250318
+)
+
+// scheduler opens scheduled eliminations at their start date, closes open
+// eliminations at their end date and finalizes the closed ones once their
+// queued votes are drained
+// Every replica runs a scheduler, but an advisory lock ensures only one of
+// them acts on each tick
+type scheduler struct {
+	db                 *sqlx.DB
+	eliminationRepo    Repository
+	eliminationService Service
+	interval           time.Duration
+	// drainSettle is how long a drained elimination waits for the votes other
+	// consumers still hold, and drainTimeout is the longest a closing one waits
+	// for its barrier
+	drainSettle  time.Duration
+	drainTimeout time.Duration
+}
+
+func NewScheduler(
+	db *sqlx.DB,
+	eliminationRepo Repository,
+	eliminationService Service,
+	interval time.Duration,
+	drainSettle time.Duration,
+	drainTimeout time.Duration,
+) *scheduler {
+	return &scheduler{
+		db:                 db,
+		eliminationRepo:    eliminationRepo,
+		eliminationService: eliminationService,
+		interval:           interval,
+		drainSettle:        drainSettle,
+		drainTimeout:       drainTimeout,
+	}
+}
+
+// Start runs the scheduler on every interval until ctx is done
+func (s *scheduler) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(s.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("stopping elimination scheduler")
+				return
+			case <-ticker.C:
+				acquired, err := util.WithAdvisoryLock(ctx, s.db, schedulerLockKey, func() error {
+					s.run(ctx, time.Now())
+					return nil
+				})
+				if err != nil {
+					slog.Error("failed to run elimination scheduler", "error", err)
+					continue
+				}
+				if !acquired {
+					slog.Debug("elimination scheduler running on another replica")
+				}
+			}
+		}
+	}()
+}
+
+func (s *scheduler) run(ctx context.Context, now time.Time) {
+	opened, err := s.eliminationRepo.OpenScheduled(ctx, now)
+	if err != nil {
+		slog.Error("failed to open scheduled eliminations", "error", err)
+	}
+	for _, eliminationId := range opened {
+		slog.Info("elimination opened", "elimination_id", eliminationId)
+	}
+
+	expired, err := s.eliminationRepo.GetExpired(ctx, now)
+	if err != nil {
+		slog.Error("failed to get expired eliminations", "error", err)
+		return
+	}
+	for _, e := range expired {
+		if err := s.eliminationService.FinishElimination(ctx, e.ID); err != nil {
+			slog.Error("failed to close elimination", "elimination_id", e.ID, "error", err)
+			continue
+		}
+		slog.Info("elimination closed", "elimination_id", e.ID)
+	}
+
+	drained, err := s.eliminationRepo.GetDrained(ctx, now, s.drainSettle, s.drainTimeout)
+	if err != nil {
+		slog.Error("failed to get drained eliminations", "error", err)
+		return
+	}
+	for _, e := range drained {
+		if e.DrainedAt == nil {
+			slog.Warn("close barrier not reached before the drain timeout", "elimination_id", e.ID)
+		}
+		if err := s.eliminationService.FinalizeElimination(ctx, e.ID); err != nil {
+			slog.Error("failed to finalize elimination", "elimination_id", e.ID, "error", err)
+			continue
+		}
+		slog.Info("elimination finished", "elimination_id", e.ID)
+	}
+}
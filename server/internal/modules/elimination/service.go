@@ -2,29 +2,55 @@ package elimination
 
 import (
 	"context"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"log/slog"
 	"math"
+	"slices"
+	"strings"
 	"time"
 
+	"github.com/bernardinorafael/globo-challenge/internal/infra/ratelimit"
+	"github.com/bernardinorafael/globo-challenge/internal/infra/signer"
 	"github.com/bernardinorafael/globo-challenge/internal/metric"
 	"github.com/bernardinorafael/globo-challenge/internal/modules/participant"
 	"github.com/bernardinorafael/globo-challenge/internal/queue"
 	"github.com/bernardinorafael/globo-challenge/internal/shared/dto"
 	"github.com/bernardinorafael/globo-challenge/internal/util"
 	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+	"github.com/bernardinorafael/globo-challenge/pkg/merkle"
+	"github.com/segmentio/ksuid"
 )
 
 const (
-	maxEliminationAllowed = 1
+	// voteQueuedTimeout is how long a vote that was neither counted nor rejected
+	// is still reported as queued
+	voteQueuedTimeout = time.Minute * 10
 )
 
+// VoteLimits are the rate limits a vote must pass, a disabled limit is skipped
+type VoteLimits struct {
+	User        ratelimit.Limit
+	IP          ratelimit.Limit
+	Elimination ratelimit.Limit
+}
+
 type service struct {
 	ctx                context.Context
 	eliminationRepo    Repository
 	participantService participant.Service
 	queue              *queue.Queue
 	metrics            *metric.Metric
+	resultBroker       *resultBroker
+	limiter            ratelimit.Limiter
+	// localLimiter keeps the elimination limit of this replica, so the votes of
+	// every replica do not contend for a single shared bucket
+	localLimiter ratelimit.Limiter
+	voteLimits   VoteLimits
+	signer       *signer.Signer
 }
 
 func NewService(
@@ -33,6 +59,10 @@ func NewService(
 	participantService participant.Service,
 	queue *queue.Queue,
 	metrics *metric.Metric,
+	resultBroker *resultBroker,
+	limiter ratelimit.Limiter,
+	voteLimits VoteLimits,
+	signer *signer.Signer,
 ) Service {
 	return &service{
 		ctx:                ctx,
@@ -40,11 +70,28 @@ func NewService(
 		participantService: participantService,
 		queue:              queue,
 		metrics:            metrics,
+		resultBroker:       resultBroker,
+		limiter:            limiter,
+		localLimiter:       ratelimit.NewMemoryLimiter(),
+		voteLimits:         voteLimits,
+		signer:             signer,
 	}
 }
 
-func (s service) GetResult(ctx context.Context, eliminationId string) ([]ParticipantResult, error) {
-	result, err := s.eliminationRepo.GetResult(ctx, eliminationId)
+// GetResult returns the result of an elimination from the vote counters, which
+// lag behind the stored votes by up to a tally flush
+// A fresh result is counted from the stored votes instead
+func (s service) GetResult(ctx context.Context, eliminationId string, fresh bool) ([]ParticipantResult, error) {
+	elimination, err := s.eliminationRepo.GetByID(ctx, eliminationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NewNotFoundError("elimination not found", err)
+		}
+		slog.Error("failed to get elimination", "error", err)
+		return nil, errs.NewBadRequestError("failed to get elimination", err)
+	}
+
+	result, err := currentResult(ctx, s.eliminationRepo, *elimination, fresh)
 	if err != nil {
 		slog.Error("failed to get elimination result", "error", err)
 		return nil, errs.NewBadRequestError("failed to get elimination result", err)
@@ -53,62 +100,241 @@ func (s service) GetResult(ctx context.Context, eliminationId string) ([]Partici
 	return result, nil
 }
 
-func (s service) GetDashboard(ctx context.Context) (*DashboardResult, error) {
-	elimination, err := s.eliminationRepo.GetUniqueOpen(ctx)
+func (s service) SubscribeResult(ctx context.Context, eliminationId string) (*ResultEvent, <-chan ResultEvent, func(), error) {
+	elimination, err := s.eliminationRepo.GetByID(ctx, eliminationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, nil, errs.NewNotFoundError("elimination not found", err)
+		}
+		return nil, nil, nil, errs.NewBadRequestError("failed to get elimination", err)
+	}
+
+	// Subscribing before reading the current result ensures no update is lost in between
+	updates, unsubscribe := s.resultBroker.Subscribe(eliminationId)
+
+	results, err := currentResult(ctx, s.eliminationRepo, *elimination, false)
 	if err != nil {
-		return nil, errs.NewBadRequestError("failed to get unique open elimination", err)
+		unsubscribe()
+		slog.Error("failed to get elimination result", "error", err)
+		return nil, nil, nil, errs.NewBadRequestError("failed to get elimination result", err)
+	}
+	current := newResultEvent(results)
+
+	return &current, updates, unsubscribe, nil
+}
+
+// GetDashboard returns the vote statistics of an elimination, with its votes
+// as a time series over its voting window
+func (s service) GetDashboard(ctx context.Context, eliminationId string, input dto.DashboardQuery) (*DashboardResult, error) {
+	bucket := Bucket(input.Bucket)
+	if bucket == "" {
+		bucket = defaultDashboardBucket
+	}
+	width, ok := bucket.Duration()
+	if !ok {
+		return nil, errs.NewUnprocessableEntityError("bucket must be one of 1m, 5m or 1h", nil)
+	}
+
+	timezone := input.Timezone
+	if timezone == "" {
+		timezone = defaultDashboardTimezone
 	}
-	if elimination == nil {
-		return nil, errs.NewBadRequestError("no open elimination found", err)
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return nil, errs.NewUnprocessableEntityError(fmt.Sprintf("unknown timezone %q", timezone), err)
 	}
 
-	votes, err := s.eliminationRepo.GetVotesByEliminationID(ctx, elimination.ID)
+	elimination, err := s.eliminationRepo.GetByID(ctx, eliminationId)
 	if err != nil {
-		slog.Error("failed to get votes by elimination id", "error", err)
-		return nil, errs.NewBadRequestError("failed to get votes by elimination id", err)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NewNotFoundError("elimination not found", err)
+		}
+		return nil, errs.NewBadRequestError("failed to get elimination", err)
+	}
+
+	from, to := votingWindow(*elimination, time.Now())
+	if err := checkSeriesSize(from, to, width); err != nil {
+		return nil, errs.NewUnprocessableEntityError(err.Error(), err)
 	}
 
-	var spreadVotes [24]int
-	for _, v := range votes {
-		hour := v.Created.Hour()
-		spreadVotes[hour]++
+	series, err := s.eliminationRepo.GetVoteSeries(ctx, eliminationId, width, timezone, from, to)
+	if err != nil {
+		slog.Error("failed to get vote series", "error", err)
+		return nil, errs.NewBadRequestError("failed to get vote series", err)
+	}
+	var windowVotes int
+	for _, p := range series {
+		windowVotes += p.Votes
 	}
 
 	// TODO: retrieve totalVotes and totalUsers in a single query
-	totalVotes, err := s.eliminationRepo.GetTotalVotes(ctx)
+	totalVotes, err := s.eliminationRepo.GetTotalVotes(ctx, eliminationId)
 	if err != nil {
 		return nil, errs.NewBadRequestError("failed to get total votes", err)
 	}
-	totalUsers, err := s.eliminationRepo.GetTotalUsers(ctx)
+	totalUsers, err := s.eliminationRepo.GetTotalUsers(ctx, eliminationId)
 	if err != nil {
 		return nil, errs.NewBadRequestError("failed to get total users", err)
 	}
+	votesByChannel, err := s.eliminationRepo.GetVotesByChannel(ctx, eliminationId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get votes by channel", err)
+	}
 
 	result := &DashboardResult{
+		EliminationID:  eliminationId,
 		TotalVotes:     totalVotes,
 		TotalUsers:     totalUsers,
-		VotesPerHour:   math.Round(float64(totalVotes)/24*10) / 10,
-		SpreadVotes:    spreadVotes,
-		HasElimination: elimination != nil,
+		VotesPerHour:   votesPerHour(windowVotes, from, to),
+		Bucket:         bucket,
+		Timezone:       timezone,
+		Series:         series,
+		Peak:           peakPoint(series),
+		VotesByChannel: votesByChannel,
+		HasElimination: true,
 	}
 
 	return result, nil
 }
 
+// GetOverview compares every elimination of the season by votes, voters,
+// duration and vote rates
+func (s service) GetOverview(ctx context.Context) ([]EliminationOverview, error) {
+	overview, err := s.eliminationRepo.GetOverview(ctx)
+	if err != nil {
+		slog.Error("failed to get overview", "error", err)
+		return nil, errs.NewBadRequestError("failed to get overview", err)
+	}
+
+	now := time.Now()
+	for i := range overview {
+		o := &overview[i]
+		from, to := votingWindow(Entity{StartDate: o.StartDate, EndDate: o.EndDate, ClosedAt: o.ClosedAt}, now)
+		if to.After(from) {
+			o.DurationHours = math.Round(to.Sub(from).Hours()*10) / 10
+		}
+		o.VotesPerHour = votesPerHour(o.TotalVotes, from, to)
+	}
+
+	return overview, nil
+}
+
+// FinishElimination closes an elimination at the current time and publishes
+// its close barrier to the votes queue
+// The result is only frozen by FinalizeElimination, once the votes cast before
+// the close have been drained from the queue
+// A closing elimination whose barrier was not reached yet gets its barrier
+// published again, so a failed publish can be retried
 func (s service) FinishElimination(ctx context.Context, eliminationId string) error {
-	elimination, err := s.eliminationRepo.GetByIDWithParticipants(ctx, eliminationId)
+	elimination, err := s.eliminationRepo.GetByID(ctx, eliminationId)
 	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.NewNotFoundError("elimination not found", err)
+		}
 		slog.Error("failed to get elimination", "error", err)
 		return errs.NewBadRequestError("failed to get elimination", err)
 	}
-	participants := elimination.Participants
+	switch {
+	case elimination.Status == StatusClosed:
+		return errs.NewConflictError("elimination already finished", nil)
+	case elimination.Status == StatusClosing && elimination.DrainedAt != nil:
+		return errs.NewConflictError("elimination is already closing", nil)
+	case elimination.Status == StatusClosing && elimination.ClosedAt != nil:
+		return s.publishBarrier(ctx, eliminationId, *elimination.ClosedAt)
+	}
+
+	closedAt := time.Now()
+	err = s.eliminationRepo.CloseElimination(ctx, eliminationId, closedAt)
+	if err != nil {
+		return errs.NewBadRequestError("failed to close elimination", err)
+	}
 
-	var participantsID []string
-	for _, v := range participants {
-		participantsID = append(participantsID, v.ID)
+	return s.publishBarrier(ctx, eliminationId, closedAt)
+}
+
+// publishBarrier publishes the close barrier of an elimination to the votes queue
+// Without its barrier the elimination is only finalized once the drain timeout
+// expires, so a failed publish is returned for the close to be retried
+func (s service) publishBarrier(ctx context.Context, eliminationId string, closedAt time.Time) error {
+	msg, err := json.Marshal(Barrier{EliminationID: eliminationId, ClosedAt: closedAt})
+	if err != nil {
+		return errs.NewBadRequestError("failed to marshal barrier message", err)
+	}
+
+	err = s.queue.PublishBarrier(ctx, queue.VotesCreatedKey, util.GenID("barrier"), msg)
+	if err != nil {
+		s.metrics.RecordError("queue_publish_error")
+		slog.Error("failed to publish close barrier", "elimination_id", eliminationId, "error", err)
+		return errs.NewInternalServerError(err)
+	}
+
+	return nil
+}
+
+// FinalizeElimination freezes the result of a closing elimination into a
+// snapshot and stores its outcome
+func (s service) FinalizeElimination(ctx context.Context, eliminationId string) error {
+	elimination, err := s.eliminationRepo.GetByID(ctx, eliminationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.NewNotFoundError("elimination not found", err)
+		}
+		slog.Error("failed to get elimination", "error", err)
+		return errs.NewBadRequestError("failed to get elimination", err)
+	}
+	if elimination.Status != StatusClosing {
+		return errs.NewForbiddenError("elimination is not closing", errs.InvalidState, nil)
+	}
+
+	// An elimination whose barrier was never reached is drained here, so a vote
+	// still in flight is rejected instead of being stored after the snapshot
+	err = s.eliminationRepo.MarkDrained(ctx, eliminationId)
+	if err != nil {
+		return errs.NewBadRequestError("failed to mark elimination as drained", err)
+	}
+
+	// The tallies of a consumer that died before flushing never reach the
+	// counters, so the counters are rebuilt from the stored votes first
+	_, err = s.eliminationRepo.RebuildCounters(ctx, eliminationId)
+	if err != nil {
+		return errs.NewBadRequestError("failed to rebuild vote counters", err)
 	}
 
-	err = s.eliminationRepo.FinishElimination(ctx, eliminationId, participantsID)
+	results, err := s.eliminationRepo.GetResult(ctx, eliminationId)
+	if err != nil {
+		slog.Error("failed to get elimination result", "error", err)
+		return errs.NewBadRequestError("failed to get elimination result", err)
+	}
+
+	snapshot := buildResult(*elimination, results)
+
+	// Outcomes can only fail when a tie cannot be settled yet
+	outcomes, err := computeOutcomes(*elimination, snapshot)
+	if err != nil {
+		return errs.NewForbiddenError(err.Error(), errs.InvalidState, err)
+	}
+
+	head, err := s.eliminationRepo.GetLedgerHead(ctx, eliminationId)
+	if err != nil {
+		return errs.NewBadRequestError("failed to get ledger head", err)
+	}
+	votes, err := s.eliminationRepo.GetCountedVotes(ctx, eliminationId)
+	if err != nil {
+		return errs.NewBadRequestError("failed to get counted votes", err)
+	}
+	tree := newMerkleTree(votes)
+	body, err := buildCertificate(*elimination, snapshot, votes, tree)
+	if err != nil {
+		return errs.NewBadRequestError("failed to build certificate", err)
+	}
+	body.LedgerEntries = head.Seq
+	body.LedgerHead = head.Hash
+	data, err := json.Marshal(body)
+	if err != nil {
+		return errs.NewBadRequestError("failed to marshal certificate", err)
+	}
+	certificate := Certificate{Body: data, Signature: s.signer.Sign(data)}
+
+	err = s.eliminationRepo.FinishElimination(ctx, eliminationId, outcomes, snapshot, certificate, tree)
 	if err != nil {
 		return errs.NewBadRequestError("failed to finish elimination", err)
 	}
@@ -116,24 +342,416 @@ func (s service) FinishElimination(ctx context.Context, eliminationId string) er
 	return nil
 }
 
-func (s service) Vote(ctx context.Context, input dto.CreateVote) error {
+// GetCertificate returns the signed result certificate of a finished elimination
+func (s service) GetCertificate(ctx context.Context, eliminationId string) (*Certificate, error) {
+	certificate, err := s.eliminationRepo.GetCertificate(ctx, eliminationId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get certificate", err)
+	}
+	if certificate == nil {
+		return nil, errs.NewNotFoundError("elimination has no certificate yet", nil)
+	}
+	certificate.PublicKey = s.signer.PublicKey()
+
+	return certificate, nil
+}
+
+// GetVoteProof returns the inclusion proof of a vote in the certified set of
+// its elimination
+// The path is read from the tree stored when the elimination was finalized, and
+// the proof is only served when it leads to the certified root
+func (s service) GetVoteProof(ctx context.Context, voteId string) (*MerkleProof, error) {
+	vote, err := s.eliminationRepo.GetVote(ctx, voteId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get vote", err)
+	}
+	if vote == nil {
+		return nil, errs.NewNotFoundError("vote not found", nil)
+	}
+
+	certificate, err := s.eliminationRepo.GetCertificate(ctx, vote.EliminationID)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get certificate", err)
+	}
+	if certificate == nil {
+		return nil, errs.NewNotFoundError("elimination has no certificate yet", nil)
+	}
+	var body CertificateBody
+	if err := json.Unmarshal(certificate.Body, &body); err != nil {
+		return nil, errs.NewBadRequestError("failed to decode certificate", err)
+	}
+
+	index, stored, err := s.eliminationRepo.GetMerkleLeaf(ctx, vote.EliminationID, vote.ID)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get merkle leaf", err)
+	}
+	if !stored && body.TotalVotes > 0 {
+		index, err = s.backfillMerkleTree(ctx, vote.EliminationID, vote.ID, body.MerkleRoot)
+		if err != nil {
+			slog.Error("failed to store merkle tree", "elimination_id", vote.EliminationID, "error", err)
+			return nil, errs.NewInternalServerError(err)
+		}
+	}
+	if index == nil {
+		return nil, errs.NewNotFoundError("vote is not in the certified set", nil)
+	}
+
+	siblings := merkle.Path(body.TotalVotes, *index)
+	var nodes = make([]merkle.Node, 0, len(siblings))
+	for _, sibling := range siblings {
+		nodes = append(nodes, sibling.Node)
+	}
+	hashes, err := s.eliminationRepo.GetMerkleNodes(ctx, vote.EliminationID, nodes)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get merkle nodes", err)
+	}
+
+	var path = make([]merkle.Step, 0, len(siblings))
+	for _, sibling := range siblings {
+		hash, ok := hashes[sibling.Node]
+		if !ok {
+			return nil, errs.NewInternalServerError(fmt.Errorf("merkle tree of elimination %s is missing a node", vote.EliminationID))
+		}
+		path = append(path, merkle.Step{Hash: hex.EncodeToString(hash), Side: sibling.Side})
+	}
+
+	proof := MerkleProof{
+		VoteID:        vote.ID,
+		EliminationID: vote.EliminationID,
+		Leaf:          hex.EncodeToString(merkle.Leaf(vote.ID)),
+		Index:         *index,
+		Path:          path,
+		Root:          body.MerkleRoot,
+	}
+	if !merkle.Verify(vote.ID, proof.Path, body.MerkleRoot) {
+		slog.Error("stored votes no longer match the certificate", "elimination_id", vote.EliminationID)
+		return nil, errs.NewInternalServerError(fmt.Errorf("votes of elimination %s do not match its certificate", vote.EliminationID))
+	}
+
+	return &proof, nil
+}
+
+// backfillMerkleTree stores the tree of a certificate issued before trees were
+// stored at finalize, and returns the position of the vote among its leaves
+func (s service) backfillMerkleTree(ctx context.Context, eliminationId, voteId, root string) (*int, error) {
+	votes, err := s.eliminationRepo.GetCountedVotes(ctx, eliminationId)
+	if err != nil {
+		return nil, err
+	}
+
+	tree := newMerkleTree(votes)
+	if tree.Root() != root {
+		return nil, fmt.Errorf("votes of elimination %s do not match its certificate", eliminationId)
+	}
+	if err := s.eliminationRepo.InsertMerkleTree(ctx, eliminationId, tree); err != nil {
+		return nil, err
+	}
+
+	index, found := slices.BinarySearch(tree.VoteIDs, voteId)
+	if !found {
+		return nil, nil
+	}
+	return &index, nil
+}
+
+func (s service) DecideTie(ctx context.Context, eliminationId string, input dto.DecideTie) error {
+	record, err := s.eliminationRepo.GetByIDWithParticipants(ctx, eliminationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return errs.NewNotFoundError("elimination not found", err)
+		}
+		slog.Error("failed to get elimination", "error", err)
+		return errs.NewBadRequestError("failed to get elimination", err)
+	}
+
+	if !slices.ContainsFunc(record.Participants, func(p Participant) bool { return p.ID == input.ParticipantID }) {
+		return errs.NewUnprocessableEntityError("participant is not in this elimination", nil)
+	}
+
+	elimination := NewEliminationFromDatabase(record.Entity)
+	if err := elimination.DecideTie(input.ParticipantID); err != nil {
+		return errs.NewForbiddenError(err.Error(), errs.InvalidState, err)
+	}
+
+	err = s.eliminationRepo.SetTieBreakDecision(ctx, elimination.Store())
+	if err != nil {
+		if errors.Is(err, errStatusChanged) {
+			return errs.NewConflictError("elimination changed status while deciding the tie, try again", err)
+		}
+		return errs.NewBadRequestError("failed to update elimination", err)
+	}
+
+	return nil
+}
+
+// RemoveParticipant takes a participant out of an open elimination mid-vote
+// ("bate-volta"), their votes are annulled and the removal is audited
+func (s service) RemoveParticipant(ctx context.Context, eliminationId string, input dto.RemoveParticipant) (*Removal, error) {
+	elimination, err := s.eliminationRepo.GetByID(ctx, eliminationId)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NewNotFoundError("elimination not found", err)
+		}
+		return nil, errs.NewBadRequestError("failed to get elimination", err)
+	}
+	if elimination.Status != StatusOpen {
+		return nil, errs.NewForbiddenError("participants can only be removed from an open elimination", errs.InvalidState, nil)
+	}
+
+	participants, err := s.eliminationRepo.GetParticipantIDs(ctx, eliminationId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get elimination participants", err)
+	}
+	if !slices.Contains(participants, input.ParticipantID) {
+		return nil, errs.NewUnprocessableEntityError("participant is not in this elimination", nil)
+	}
+	if len(participants)-1 <= max(elimination.EliminatedCount, 1) {
+		return nil, errs.NewForbiddenError(
+			"the elimination must keep more participants than it eliminates",
+			errs.InvalidState,
+			nil,
+		)
+	}
+
+	removal, err := s.eliminationRepo.RemoveParticipant(ctx, Removal{
+		EliminationID: eliminationId,
+		ParticipantID: input.ParticipantID,
+		Reason:        input.Reason,
+		ActorID:       input.ActorID,
+		Removed:       time.Now(),
+	})
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to remove participant", err)
+	}
+
+	// The voters who only voted for the removed participant are dropped by a
+	// rebuild, the next one fixes them when this one fails
+	_, err = s.eliminationRepo.RebuildCounters(ctx, eliminationId)
+	if err != nil {
+		slog.Error("failed to rebuild vote counters", "elimination_id", eliminationId, "error", err)
+	}
+
+	return removal, nil
+}
+
+// GetEvents returns the audit events of an elimination
+func (s service) GetEvents(ctx context.Context, eliminationId string) ([]Event, error) {
+	events, err := s.eliminationRepo.GetEvents(ctx, eliminationId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get elimination events", err)
+	}
+
+	return events, nil
+}
+
+func (s service) GetOutcomes(ctx context.Context, eliminationId string) ([]Outcome, error) {
+	outcomes, err := s.eliminationRepo.GetOutcomes(ctx, eliminationId)
+	if err != nil {
+		slog.Error("failed to get elimination outcomes", "error", err)
+		return nil, errs.NewBadRequestError("failed to get elimination outcomes", err)
+	}
+	if len(outcomes) == 0 {
+		return nil, errs.NewNotFoundError("elimination has no outcome yet", nil)
+	}
+
+	return outcomes, nil
+}
+
+func (s service) Vote(ctx context.Context, input dto.CreateVote) (*Receipt, error) {
+	err := s.checkVoteLimits(ctx, input)
+	if err != nil {
+		return nil, err
+	}
+
+	elimination, err := s.eliminationRepo.GetByID(ctx, input.EliminationID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, errs.NewNotFoundError("elimination not found", err)
+		}
+		return nil, errs.NewBadRequestError("failed to get elimination", err)
+	}
+
+	participants, err := s.eliminationRepo.GetParticipantIDs(ctx, input.EliminationID)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get elimination participants", err)
+	}
+
 	vote := Vote{
 		ID:            util.GenID("vote"),
 		UserID:        input.UserID,
 		EliminationID: input.EliminationID,
 		ParticipantID: input.ParticipantID,
+		Origin:        input.Origin,
+		Pool:          Pool(input.Pool),
 		Created:       time.Now(),
 	}
+	if vote.Pool == "" {
+		vote.Pool = PoolFan
+	}
+
+	err = checkVote(*elimination, participants, vote, vote.Created)
+	if err != nil {
+		return nil, err
+	}
+
+	if vote.Pool == PoolVerified {
+		err = s.checkVerifiedVote(ctx, input)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	receipt := vote.receipt()
+	receipt.Signature = s.signer.Sign(receipt.Payload())
+	vote.Signature = receipt.Signature
 
 	msg, err := json.Marshal(vote)
 	if err != nil {
-		return errs.NewBadRequestError("failed to marshal vote message", err)
+		return nil, errs.NewBadRequestError("failed to marshal vote message", err)
 	}
 
-	err = s.queue.Publish(ctx, queue.VotesCreatedKey, msg)
+	err = s.queue.Publish(ctx, queue.VotesCreatedKey, vote.ID, msg)
 	if err != nil {
 		s.metrics.RecordError("queue_publish_error")
-		return errs.NewBadRequestError("failed to publish vote to queue", err)
+		return nil, errs.NewBadRequestError("failed to publish vote to queue", err)
+	}
+
+	return &receipt, nil
+}
+
+// GetVoteStatus tells the owner of a vote whether it was counted or rejected
+// A vote that is neither is still queued, unless it is older than
+// voteQueuedTimeout, in which case it is treated as unknown
+func (s service) GetVoteStatus(ctx context.Context, voteId, userId string) (*VoteLookup, error) {
+	vote, err := s.eliminationRepo.GetVote(ctx, voteId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get vote", err)
+	}
+	if vote != nil {
+		if vote.UserID != userId {
+			return nil, errs.NewNotFoundError("vote not found", nil)
+		}
+		lookup := VoteLookup{
+			ID:            vote.ID,
+			EliminationID: vote.EliminationID,
+			ParticipantID: vote.ParticipantID,
+			Status:        VoteCounted,
+		}
+
+		// The votes of a participant removed from the elimination are annulled
+		participants, err := s.eliminationRepo.GetParticipantIDs(ctx, vote.EliminationID)
+		if err != nil {
+			return nil, errs.NewBadRequestError("failed to get elimination participants", err)
+		}
+		if !slices.Contains(participants, vote.ParticipantID) {
+			lookup.Status = VoteAnnulled
+		}
+
+		return &lookup, nil
+	}
+
+	rejection, err := s.eliminationRepo.GetRejection(ctx, voteId)
+	if err != nil {
+		return nil, errs.NewBadRequestError("failed to get vote rejection", err)
+	}
+	if rejection != nil {
+		if rejection.UserID != userId {
+			return nil, errs.NewNotFoundError("vote not found", nil)
+		}
+		return &VoteLookup{
+			ID:            rejection.VoteID,
+			EliminationID: rejection.EliminationID,
+			Status:        VoteRejected,
+			Code:          rejection.Code,
+			Reason:        rejection.Reason,
+		}, nil
+	}
+
+	// Vote IDs carry the time they were generated at
+	id, err := ksuid.Parse(strings.TrimPrefix(voteId, "vote_"))
+	if err != nil || time.Since(id.Time()) > voteQueuedTimeout {
+		return nil, errs.NewNotFoundError("vote not found", err)
+	}
+
+	return &VoteLookup{ID: voteId, Status: VoteQueued}, nil
+}
+
+// VerifyReceipt checks the signature of a vote receipt
+func (s service) VerifyReceipt(ctx context.Context, receipt Receipt) ReceiptVerification {
+	return ReceiptVerification{
+		Valid:     s.signer.Verify(receipt.Payload(), receipt.Signature),
+		PublicKey: s.signer.PublicKey(),
+	}
+}
+
+// checkVoteLimits takes a token from the elimination, IP and user buckets of a vote
+// The elimination bucket is kept by each replica, so it never becomes a hot row
+// shared by every vote, and the tokens of a rejected vote are refunded
+// Voting stays available when the limiter fails, the error is only logged
+func (s service) checkVoteLimits(ctx context.Context, input dto.CreateVote) error {
+	type check struct {
+		scope   string
+		key     string
+		limit   ratelimit.Limit
+		limiter ratelimit.Limiter
+	}
+
+	// The local elimination limit goes first, so a flood rejected there never
+	// reaches the shared buckets
+	var checks = []check{
+		{"elimination", input.EliminationID, s.voteLimits.Elimination, s.localLimiter},
+		{"ip", input.IP, s.voteLimits.IP, s.limiter},
+		{"user", input.UserID, s.voteLimits.User, s.limiter},
+	}
+
+	var taken []check
+	for _, c := range checks {
+		if !c.limit.Enabled() || c.key == "" {
+			continue
+		}
+
+		res, err := c.limiter.Allow(ctx, "vote:"+c.scope+":"+c.key, c.limit)
+		if err != nil {
+			slog.Error("failed to check vote rate limit", "scope", c.scope, "error", err)
+			s.metrics.RecordError("rate_limit_error")
+			continue
+		}
+		if !res.Allowed {
+			// A rejected vote does not count, so the tokens it already took are returned
+			for _, t := range taken {
+				if err := t.limiter.Refund(ctx, "vote:"+t.scope+":"+t.key, t.limit); err != nil {
+					slog.Error("failed to refund vote rate limit", "scope", t.scope, "error", err)
+					s.metrics.RecordError("rate_limit_error")
+				}
+			}
+
+			s.metrics.RecordRateLimited(c.scope)
+			return errs.NewTooManyRequestsError(
+				fmt.Sprintf("too many votes, try again in %s", res.RetryAfter.Round(time.Second)),
+				res.RetryAfter,
+				nil,
+			)
+		}
+		taken = append(taken, c)
+	}
+
+	return nil
+}
+
+// checkVerifiedVote ensures only verified users cast a verified vote, and only
+// once per elimination
+// A second verified vote that slips past this check is recorded as rejected by the consumer
+func (s service) checkVerifiedVote(ctx context.Context, input dto.CreateVote) error {
+	if !input.Verified {
+		return errs.NewForbiddenError("only verified users can cast a verified vote", errs.InvalidState, nil)
+	}
+
+	voted, err := s.eliminationRepo.HasVerifiedVote(ctx, input.EliminationID, input.UserID)
+	if err != nil {
+		return errs.NewBadRequestError("failed to check verified vote", err)
+	}
+	if voted {
+		return errs.NewConflictError("verified vote already cast in this elimination", nil)
 	}
 
 	return nil
@@ -158,39 +776,36 @@ func (s service) GetAllOpen(ctx context.Context) ([]EntityWithParticipants, erro
 	return eliminations, nil
 }
 
-func (s service) CreateElimination(ctx context.Context, participants []string) error {
-	eliminations, err := s.eliminationRepo.GetAll(ctx)
+func (s service) CreateElimination(ctx context.Context, input dto.CreateElimination) error {
+	newElimination, err := NewElimination(Settings{
+		StartDate:        input.StartDate,
+		EndDate:          input.EndDate,
+		Type:             PollType(input.Type),
+		VoteMode:         VoteMode(input.VoteMode),
+		EliminatedCount:  input.EliminatedCount,
+		Participants:     input.Participants,
+		TieBreakPolicy:   TieBreakPolicy(input.TieBreakPolicy),
+		TieBreakPriority: input.TieBreakPriority,
+		VotingScheme:     VotingScheme(input.VotingScheme),
+		VerifiedWeight:   input.VerifiedWeight,
+		FanWeight:        input.FanWeight,
+	})
 	if err != nil {
-		return errs.NewBadRequestError("failed to get eliminations", err)
-	}
-
-	var openEliminations []EntityWithParticipants
-	for _, elimination := range eliminations {
-		if elimination.Open {
-			openEliminations = append(openEliminations, elimination)
-		}
+		return errs.NewUnprocessableEntityError(err.Error(), err)
 	}
 
-	if len(openEliminations) >= maxEliminationAllowed {
-		return errs.NewForbiddenError(
-			"only 1 enabled elimination is allowed",
-			errs.ResourceLimitReached,
-			nil,
-		)
-	}
-
-	newElimination := NewElimination()
-
-	err = s.eliminationRepo.Insert(ctx, newElimination.Store())
+	// The participants are checked while locked, so one withdrawn meanwhile
+	// cannot end up in the elimination
+	err = s.eliminationRepo.Insert(ctx, newElimination.Store(), newElimination.Participants())
 	if err != nil {
-		return errs.NewBadRequestError("failed to create elimination", err)
-	}
-
-	for _, participantId := range participants {
-		err = s.participantService.AssignElimination(ctx, participantId, newElimination.ID())
-		if err != nil {
-			return errs.NewBadRequestError("failed to assign elimination to participant", err)
+		var ineligible ineligibleParticipantError
+		switch {
+		case errors.Is(err, errParticipantNotFound):
+			return errs.NewNotFoundError("participant not found", err)
+		case errors.As(err, &ineligible):
+			return errs.NewForbiddenError(ineligible.Error(), errs.InvalidState, nil)
 		}
+		return errs.NewBadRequestError("failed to create elimination", err)
 	}
 
 	return nil
@@ -0,0 +1,176 @@
+package elimination
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+const (
+	// votesChannel is the PostgreSQL channel notified with the elimination ID
+	// every time votes of that elimination are committed
+	votesChannel = "elimination_votes"
+	// subscriberBuffer is how many result events a slow subscriber may lag behind
+	subscriberBuffer = 1
+)
+
+// ResultEvent is a result snapshot pushed to the stream subscribers
+// The ID is the total number of votes counted, so clients reconnecting with
+// the same Last-Event-ID already hold the latest result
+type ResultEvent struct {
+	ID      string              `json:"id"`
+	Results []ParticipantResult `json:"results"`
+}
+
+func newResultEvent(results []ParticipantResult) ResultEvent {
+	var total int
+	for _, r := range results {
+		total += r.Count
+	}
+	return ResultEvent{ID: strconv.Itoa(total), Results: results}
+}
+
+// resultBroker fans out elimination results to the stream subscribers of this replica
+// Replicas learn about new votes through PostgreSQL LISTEN/NOTIFY, and results
+// are recomputed at most once per interval for each elimination with subscribers
+type resultBroker struct {
+	dsn             string
+	eliminationRepo Repository
+	interval        time.Duration
+	mu              sync.Mutex
+	subscribers     map[string]map[chan ResultEvent]struct{}
+	dirty           map[string]struct{}
+}
+
+func NewResultBroker(dsn string, eliminationRepo Repository, interval time.Duration) *resultBroker {
+	return &resultBroker{
+		dsn:             dsn,
+		eliminationRepo: eliminationRepo,
+		interval:        interval,
+		subscribers:     make(map[string]map[chan ResultEvent]struct{}),
+		dirty:           make(map[string]struct{}),
+	}
+}
+
+// Start listens for vote notifications and pushes results until ctx is done
+func (b *resultBroker) Start(ctx context.Context) error {
+	listener := pq.NewListener(b.dsn, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			slog.Error("result broker listener event", "event", ev, "error", err)
+		}
+	})
+	if err := listener.Listen(votesChannel); err != nil {
+		_ = listener.Close()
+		return fmt.Errorf("failed to listen to %s: %w", votesChannel, err)
+	}
+
+	go func() {
+		defer listener.Close()
+
+		ticker := time.NewTicker(b.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("stopping result broker")
+				return
+			case n := <-listener.Notify:
+				// A nil notification means the connection was re-established
+				// and notifications may have been missed meanwhile
+				if n == nil {
+					b.markAllDirty()
+					continue
+				}
+				b.markDirty(n.Extra)
+			case <-ticker.C:
+				b.publish(ctx)
+			}
+		}
+	}()
+
+	return nil
+}
+
+// Subscribe registers a subscriber for the results of an elimination
+// The returned function must be called to release the subscription
+func (b *resultBroker) Subscribe(eliminationId string) (<-chan ResultEvent, func()) {
+	ch := make(chan ResultEvent, subscriberBuffer)
+
+	b.mu.Lock()
+	if b.subscribers[eliminationId] == nil {
+		b.subscribers[eliminationId] = make(map[chan ResultEvent]struct{})
+	}
+	b.subscribers[eliminationId][ch] = struct{}{}
+	b.mu.Unlock()
+
+	unsubscribe := func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		delete(b.subscribers[eliminationId], ch)
+		if len(b.subscribers[eliminationId]) == 0 {
+			delete(b.subscribers, eliminationId)
+		}
+	}
+
+	return ch, unsubscribe
+}
+
+func (b *resultBroker) markDirty(eliminationId string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if _, ok := b.subscribers[eliminationId]; ok {
+		b.dirty[eliminationId] = struct{}{}
+	}
+}
+
+func (b *resultBroker) markAllDirty() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for eliminationId := range b.subscribers {
+		b.dirty[eliminationId] = struct{}{}
+	}
+}
+
+// publish recomputes the result of every changed elimination and sends it to its subscribers
+func (b *resultBroker) publish(ctx context.Context) {
+	b.mu.Lock()
+	var changed []string
+	for eliminationId := range b.dirty {
+		changed = append(changed, eliminationId)
+	}
+	b.dirty = make(map[string]struct{})
+	b.mu.Unlock()
+
+	for _, eliminationId := range changed {
+		elimination, err := b.eliminationRepo.GetByID(ctx, eliminationId)
+		if err != nil {
+			slog.Error("failed to get elimination for stream", "elimination_id", eliminationId, "error", err)
+			b.markDirty(eliminationId)
+			continue
+		}
+		results, err := currentResult(ctx, b.eliminationRepo, *elimination, false)
+		if err != nil {
+			slog.Error("failed to get result for stream", "elimination_id", eliminationId, "error", err)
+			b.markDirty(eliminationId)
+			continue
+		}
+		event := newResultEvent(results)
+
+		b.mu.Lock()
+		for ch := range b.subscribers[eliminationId] {
+			// A subscriber that has not read the previous event only needs the latest one
+			select {
+			case <-ch:
+			default:
+			}
+			ch <- event
+		}
+		b.mu.Unlock()
+	}
+}
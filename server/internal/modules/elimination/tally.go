@@ -0,0 +1,199 @@
+package elimination
+
+import (
+	"context"
+	"hash/maphash"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/metric"
+)
+
+// tallyShards is the number of shards the tallies are spread over, so
+// concurrent writers rarely contend for the same lock
+const tallyShards = 32
+
+type tallyKey struct {
+	eliminationId string
+	participantId string
+	origin        string
+	pool          Pool
+	bucket        time.Time
+	epoch         int64
+}
+
+type tallyCount struct {
+	count atomic.Int64
+	// reachedAt is the Unix time in nanoseconds of the latest vote
+	reachedAt atomic.Int64
+}
+
+type tallyShard struct {
+	mu     sync.RWMutex
+	counts map[tallyKey]*tallyCount
+}
+
+// Tally keeps the votes stored since the last flush as in-memory counts and
+// adds them to the vote counters on every interval
+// Counts are lost when the process dies before a flush, the counters are then
+// rebuilt from the stored votes when the consumer starts and when an
+// elimination is finalized
+type Tally struct {
+	eliminationRepo Repository
+	metrics         *metric.Metric
+	interval        time.Duration
+	seed            maphash.Seed
+	shards          [tallyShards]tallyShard
+	// flushMu serializes the flushes of the ticker and of the close barriers
+	flushMu sync.Mutex
+}
+
+func NewTally(eliminationRepo Repository, metrics *metric.Metric, interval time.Duration) *Tally {
+	t := &Tally{
+		eliminationRepo: eliminationRepo,
+		metrics:         metrics,
+		interval:        interval,
+		seed:            maphash.MakeSeed(),
+	}
+	for i := range t.shards {
+		t.shards[i].counts = make(map[tallyKey]*tallyCount)
+	}
+	return t
+}
+
+// Start flushes the tallies on every interval until ctx is done
+// The last flush is made by the consumer, after its last batch of votes
+func (t *Tally) Start(ctx context.Context) {
+	go func() {
+		ticker := time.NewTicker(t.interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-ctx.Done():
+				slog.Info("stopping vote tally")
+				return
+			case <-ticker.C:
+				if err := t.Flush(ctx); err != nil {
+					t.metrics.RecordError("tally_flush_error")
+					slog.Error("failed to flush vote tally", "error", err)
+				}
+			}
+		}
+	}()
+}
+
+// Add counts the votes of an insertion
+func (t *Tally) Add(insertion *Insertion) {
+	for _, v := range insertion.Votes {
+		t.add(tallyKey{
+			eliminationId: v.EliminationID,
+			participantId: v.ParticipantID,
+			origin:        v.Origin,
+			pool:          v.Pool,
+			bucket:        v.Created.Truncate(counterBucket),
+			epoch:         insertion.Epochs[v.EliminationID],
+		}, 1, v.Created.UnixNano())
+	}
+}
+
+func (t *Tally) add(key tallyKey, n, reachedAt int64) {
+	shard := &t.shards[t.shard(key)]
+
+	shard.mu.RLock()
+	c, ok := shard.counts[key]
+	if ok {
+		// The read lock is held while counting, so a flush never swaps the
+		// shard out between the lookup and the add
+		addCount(c, n, reachedAt)
+		shard.mu.RUnlock()
+		return
+	}
+	shard.mu.RUnlock()
+
+	shard.mu.Lock()
+	c, ok = shard.counts[key]
+	if !ok {
+		c = &tallyCount{}
+		shard.counts[key] = c
+	}
+	addCount(c, n, reachedAt)
+	shard.mu.Unlock()
+}
+
+func addCount(c *tallyCount, n, reachedAt int64) {
+	c.count.Add(n)
+	for {
+		current := c.reachedAt.Load()
+		if reachedAt <= current || c.reachedAt.CompareAndSwap(current, reachedAt) {
+			return
+		}
+	}
+}
+
+func (t *Tally) shard(key tallyKey) int {
+	var h maphash.Hash
+	h.SetSeed(t.seed)
+	h.WriteString(key.eliminationId)
+	h.WriteString(key.participantId)
+	h.WriteString(key.origin)
+	return int(h.Sum64() % tallyShards)
+}
+
+// Flush adds the tallied counts to the vote counters
+// Counts that fail to be flushed are merged back to be retried on the next flush
+func (t *Tally) Flush(ctx context.Context) error {
+	t.flushMu.Lock()
+	defer t.flushMu.Unlock()
+
+	var deltas []CounterDelta
+	for i := range t.shards {
+		shard := &t.shards[i]
+
+		shard.mu.Lock()
+		counts := shard.counts
+		shard.counts = make(map[tallyKey]*tallyCount, len(counts))
+		shard.mu.Unlock()
+
+		for key, c := range counts {
+			deltas = append(deltas, CounterDelta{
+				EliminationID: key.eliminationId,
+				ParticipantID: key.participantId,
+				Origin:        key.origin,
+				Pool:          key.pool,
+				Bucket:        key.bucket,
+				Epoch:         key.epoch,
+				Count:         c.count.Load(),
+				ReachedAt:     time.Unix(0, c.reachedAt.Load()),
+			})
+		}
+	}
+	if len(deltas) == 0 {
+		return nil
+	}
+
+	var timer = t.metrics.ObserveVotingLatency("vote_tally_flush")
+	defer timer.ObserveDuration()
+
+	dropped, err := t.eliminationRepo.FlushCounters(ctx, deltas)
+	if err != nil {
+		for _, d := range deltas {
+			t.add(tallyKey{
+				eliminationId: d.EliminationID,
+				participantId: d.ParticipantID,
+				origin:        d.Origin,
+				pool:          d.Pool,
+				bucket:        d.Bucket,
+				epoch:         d.Epoch,
+			}, d.Count, d.ReachedAt.UnixNano())
+		}
+		return err
+	}
+	if dropped > 0 {
+		slog.Info("dropped vote tallies of a previous counter epoch or a removed participant", "deltas", dropped)
+	}
+
+	return nil
+}
@@ -1,23 +1,168 @@
 package elimination
 
-import "time"
+import (
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// Status is the lifecycle state of an elimination
+type Status string
+
+const (
+	// StatusScheduled is an elimination waiting for its start date
+	StatusScheduled Status = "scheduled"
+	// StatusOpen is an elimination accepting votes
+	StatusOpen Status = "open"
+	// StatusClosing is an elimination that stopped accepting new votes and waits
+	// for the votes published before it closed to be drained from the queue
+	StatusClosing Status = "closing"
+	// StatusClosed is a finished elimination
+	StatusClosed Status = "closed"
+)
+
+// PollType defines what a poll decides and how its result is interpreted
+// Every poll counts its votes with its own voting scheme, the type only
+// changes what the most voted participant gets
+type PollType string
+
+const (
+	// PollElimination eliminates the most voted participant ("paredão")
+	PollElimination PollType = "elimination"
+	// PollImmunity grants immunity to the most voted participant
+	PollImmunity PollType = "immunity"
+	// PollFinale crowns the most voted participant as the winner of the season
+	PollFinale PollType = "finale"
+)
+
+// IsValid reports whether t is a known poll type
+func (t PollType) IsValid() bool {
+	switch t {
+	case PollElimination, PollImmunity, PollFinale:
+		return true
+	default:
+		return false
+	}
+}
+
+// VoteMode defines whether an elimination poll asks who should leave or who should stay
+type VoteMode string
+
+const (
+	// VoteToEliminate eliminates the participant with the most votes
+	VoteToEliminate VoteMode = "eliminate"
+	// VoteToSave eliminates the participant with the fewest votes, every vote saves its participant
+	VoteToSave VoteMode = "save"
+)
+
+// IsValid reports whether m is a known vote mode
+func (m VoteMode) IsValid() bool {
+	return m == VoteToEliminate || m == VoteToSave
+}
+
+// TieBreakPolicy defines who the poll decides on when the most voted participants are tied
+type TieBreakPolicy string
+
+const (
+	// TieBreakEarliestVote picks the tied participant who reached the final count first
+	TieBreakEarliestVote TieBreakPolicy = "earliest_vote"
+	// TieBreakPriority picks the tied participant who comes first in a fixed priority list
+	TieBreakPriority TieBreakPolicy = "priority"
+	// TieBreakManual picks the tied participant chosen by an admin
+	TieBreakManual TieBreakPolicy = "manual"
+)
+
+// IsValid reports whether p is a known tie-break policy
+func (p TieBreakPolicy) IsValid() bool {
+	switch p {
+	case TieBreakEarliestVote, TieBreakPriority, TieBreakManual:
+		return true
+	default:
+		return false
+	}
+}
+
+// VotingScheme defines how the votes of an elimination are counted
+type VotingScheme string
+
+const (
+	// VotingSchemeSingle counts every vote the same and allows unlimited votes
+	VotingSchemeSingle VotingScheme = "single"
+	// VotingSchemeDualPool combines one verified vote per user with unlimited
+	// fan votes, each pool weighted by the elimination weights
+	VotingSchemeDualPool VotingScheme = "dual_pool"
+)
+
+// IsValid reports whether s is a known voting scheme
+func (s VotingScheme) IsValid() bool {
+	return s == VotingSchemeSingle || s == VotingSchemeDualPool
+}
+
+// Pool is the vote pool a vote is counted in
+type Pool string
+
+const (
+	// PoolVerified holds the single vote verified users get in a dual-pool elimination ("voto único")
+	PoolVerified Pool = "verified"
+	// PoolFan holds unlimited votes ("voto torcida"), every vote of a single scheme elimination is a fan vote
+	PoolFan Pool = "fan"
+)
 
 type Entity struct {
-	ID        string    `json:"id" db:"id"`
-	Open      bool      `json:"open" db:"open"`
-	StartDate time.Time `json:"start_date" db:"start_date"`
-	EndDate   time.Time `json:"end_date" db:"end_date"`
-	Created   time.Time `json:"created" db:"created"`
-	Updated   time.Time `json:"updated" db:"updated"`
+	ID   string   `json:"id" db:"id"`
+	Open bool     `json:"open" db:"open"`
+	Type PollType `json:"type" db:"type"`
+	// VoteMode is only VoteToSave in elimination polls asking who should stay
+	VoteMode VoteMode `json:"vote_mode" db:"vote_mode"`
+	// EliminatedCount is how many participants an elimination poll eliminates ("paredão duplo")
+	EliminatedCount int            `json:"eliminated_count" db:"eliminated_count"`
+	Status          Status         `json:"status" db:"status"`
+	StartDate       time.Time      `json:"start_date" db:"start_date"`
+	EndDate         time.Time      `json:"end_date" db:"end_date"`
+	TieBreakPolicy  TieBreakPolicy `json:"tie_break_policy" db:"tie_break_policy"`
+	// TieBreakPriority is the order used by the priority tie-break policy
+	TieBreakPriority pq.StringArray `json:"tie_break_priority" db:"tie_break_priority"`
+	// TieBreakDecision is the participant chosen by an admin under the manual policy
+	TieBreakDecision *string      `json:"tie_break_decision" db:"tie_break_decision"`
+	VotingScheme     VotingScheme `json:"voting_scheme" db:"voting_scheme"`
+	// VerifiedWeight and FanWeight are the percentage weights of each pool in a dual-pool elimination
+	VerifiedWeight int `json:"verified_weight" db:"verified_weight"`
+	FanWeight      int `json:"fan_weight" db:"fan_weight"`
+	// ClosedAt is the close barrier, only votes cast before it are counted
+	ClosedAt *time.Time `json:"closed_at" db:"closed_at"`
+	// DrainedAt is when the consumer reached the close barrier in the votes queue
+	DrainedAt *time.Time `json:"drained_at" db:"drained_at"`
+	Created   time.Time  `json:"created" db:"created"`
+	Updated   time.Time  `json:"updated" db:"updated"`
 }
 
 type Vote struct {
-	ID            string    `json:"id" db:"id"`
-	UserID        string    `json:"user_id" db:"user_id"`
-	EliminationID string    `json:"elimination_id" db:"elimination_id"`
-	ParticipantID string    `json:"participant_id" db:"participant_id"`
-	Created       time.Time `json:"created" db:"created"`
-	Updated       time.Time `json:"updated" db:"updated"`
+	ID            string `json:"id" db:"id"`
+	UserID        string `json:"user_id" db:"user_id"`
+	EliminationID string `json:"elimination_id" db:"elimination_id"`
+	ParticipantID string `json:"participant_id" db:"participant_id"`
+	// Origin is the channel the vote was cast from
+	Origin  string    `json:"origin" db:"origin"`
+	Pool    Pool      `json:"pool" db:"pool"`
+	Created time.Time `json:"created" db:"created"`
+	Updated time.Time `json:"updated" db:"updated"`
+	// Signature is the receipt signature the API made when the vote was cast,
+	// it travels with the queued vote so the consumer can trust its cast time
+	Signature string `json:"signature,omitempty" db:"-"`
+}
+
+// receipt returns the receipt of the vote, without its signature
+func (v Vote) receipt() Receipt {
+	return Receipt{
+		VoteID:        v.ID,
+		EliminationID: v.EliminationID,
+		ParticipantID: v.ParticipantID,
+		Pool:          v.Pool,
+		Created:       v.Created,
+	}
 }
 
 type Participant struct {
@@ -34,12 +179,166 @@ type ParticipantResult struct {
 	ID    string `json:"id" db:"id"`
 	Name  string `json:"name" db:"name"`
 	Count int    `json:"count" db:"count"`
+	// Percentage is the share of the total votes, all percentages add up to 100
+	Percentage float64 `json:"percentage" db:"-"`
+	TotalVotes int     `json:"total_votes" db:"-"`
+	// VoteMode tells whether the percentages are votes to eliminate or to save
+	VoteMode VoteMode `json:"vote_mode" db:"-"`
+	// Channels breaks the count down by vote origin
+	Channels ChannelCounts `json:"channels" db:"channels"`
+	// Pools breaks the count down by vote pool in dual-pool eliminations, where
+	// Percentage is the weighted combination of the pool percentages
+	Pools         map[Pool]PoolResult `json:"pools,omitempty" db:"-"`
+	VerifiedCount int                 `json:"-" db:"verified_count"`
+	FanCount      int                 `json:"-" db:"fan_count"`
+	// score is the value the participants are ranked by
+	score *big.Int
+	// ReachedAt is when the participant received its latest vote
+	ReachedAt *time.Time `json:"reached_at" db:"reached_at"`
+	// Tied is set on every participant sharing the deciding score with another
+	// one, the most votes or the fewest when voting to save
+	Tied bool `json:"tied" db:"-"`
+}
+
+// PoolResult is the result of a participant within a single vote pool
+type PoolResult struct {
+	Count      int     `json:"count"`
+	Percentage float64 `json:"percentage"`
+}
+
+// Outcome is the final result of a participant in a finished elimination
+type Outcome struct {
+	EliminationID string  `json:"elimination_id" db:"elimination_id"`
+	ParticipantID string  `json:"participant_id" db:"participant_id"`
+	Name          string  `json:"name" db:"name"`
+	Votes         int     `json:"votes" db:"votes"`
+	Percentage    float64 `json:"percentage" db:"percentage"`
+	Eliminated    bool    `json:"eliminated" db:"eliminated"`
+	// Winner is set on the participant who won an immunity or finale poll
+	Winner bool `json:"winner" db:"winner"`
+	// Tied is set when the participant was tied for the decision and the tie-break policy decided
+	Tied bool `json:"tied" db:"tied"`
+}
+
+// ChannelCounts maps a vote origin to its number of votes
+type ChannelCounts map[string]int
+
+// Scan reads a JSON object of counts built by the database
+func (c *ChannelCounts) Scan(src any) error {
+	var data []byte
+	switch v := src.(type) {
+	case []byte:
+		data = v
+	case string:
+		data = []byte(v)
+	case nil:
+		*c = ChannelCounts{}
+		return nil
+	default:
+		return fmt.Errorf("unsupported channel counts type %T", src)
+	}
+
+	return json.Unmarshal(data, c)
 }
 
 type DashboardResult struct {
-	TotalVotes     int     `json:"total_votes" db:"total_votes"`
-	TotalUsers     int     `json:"total_users" db:"total_users"`
-	VotesPerHour   float64 `json:"votes_per_hour" db:"votes_per_hour"`
-	SpreadVotes    [24]int `json:"spread_votes" db:"spread_votes"`
-	HasElimination bool    `json:"has_elimination" db:"has_elimination"`
+	EliminationID string `json:"elimination_id" db:"-"`
+	TotalVotes    int    `json:"total_votes" db:"total_votes"`
+	TotalUsers    int    `json:"total_users" db:"total_users"`
+	// VotesPerHour is the vote rate of the elimination over its voting window
+	VotesPerHour float64 `json:"votes_per_hour" db:"votes_per_hour"`
+	Bucket       Bucket  `json:"bucket" db:"-"`
+	Timezone     string  `json:"timezone" db:"-"`
+	// Series holds the votes of every bucket of the voting window, buckets
+	// without votes included
+	Series []SeriesPoint `json:"series" db:"-"`
+	// Peak is the bucket with the most votes, nil before the first vote
+	Peak           *SeriesPoint  `json:"peak" db:"-"`
+	VotesByChannel ChannelCounts `json:"votes_by_channel" db:"votes_by_channel"`
+	HasElimination bool          `json:"has_elimination" db:"has_elimination"`
+}
+
+// EliminationOverview is the summary of an elimination in the season overview
+type EliminationOverview struct {
+	EliminationID string     `json:"elimination_id" db:"elimination_id"`
+	Type          PollType   `json:"type" db:"type"`
+	Status        Status     `json:"status" db:"status"`
+	StartDate     time.Time  `json:"start_date" db:"start_date"`
+	EndDate       time.Time  `json:"end_date" db:"end_date"`
+	ClosedAt      *time.Time `json:"closed_at" db:"closed_at"`
+	TotalVotes    int        `json:"total_votes" db:"total_votes"`
+	UniqueVoters  int        `json:"unique_voters" db:"unique_voters"`
+	// DurationHours is the length of the voting window, up to now while the elimination is open
+	DurationHours float64 `json:"duration_hours" db:"-"`
+	VotesPerHour  float64 `json:"votes_per_hour" db:"-"`
+	// PeakVotesPerMinute is the most votes counted in a single minute
+	PeakVotesPerMinute int `json:"peak_votes_per_minute" db:"peak_votes_per_minute"`
+}
+
+// Barrier is the message published to the votes queue when an elimination closes
+type Barrier struct {
+	EliminationID string    `json:"elimination_id"`
+	ClosedAt      time.Time `json:"closed_at"`
+}
+
+// VoteStatus is the processing state of a vote
+type VoteStatus string
+
+const (
+	// VoteQueued is a vote accepted by the API and not yet processed
+	VoteQueued VoteStatus = "queued"
+	// VoteCounted is a vote stored in its elimination
+	VoteCounted VoteStatus = "counted"
+	// VoteRejected is a vote the consumer refused to count
+	VoteRejected VoteStatus = "rejected"
+	// VoteAnnulled is a stored vote for a participant removed from the elimination
+	VoteAnnulled VoteStatus = "annulled"
+)
+
+// Receipt is the signed proof given to a user that a vote was accepted
+type Receipt struct {
+	VoteID        string    `json:"vote_id"`
+	EliminationID string    `json:"elimination_id"`
+	ParticipantID string    `json:"participant_id"`
+	Pool          Pool      `json:"pool"`
+	Created       time.Time `json:"created"`
+	Signature     string    `json:"signature"`
+}
+
+// Payload returns the bytes the receipt signature covers
+func (r Receipt) Payload() []byte {
+	return []byte(fmt.Sprintf(
+		"%s|%s|%s|%s|%s",
+		r.VoteID,
+		r.EliminationID,
+		r.ParticipantID,
+		r.Pool,
+		r.Created.UTC().Format(time.RFC3339Nano),
+	))
+}
+
+// Rejection records why the consumer refused to count a vote
+type Rejection struct {
+	VoteID        string    `json:"vote_id" db:"vote_id"`
+	UserID        string    `json:"user_id" db:"user_id"`
+	EliminationID string    `json:"elimination_id" db:"elimination_id"`
+	Code          string    `json:"code" db:"code"`
+	Reason        string    `json:"reason" db:"reason"`
+	Created       time.Time `json:"created" db:"created"`
+}
+
+// VoteLookup is the status of a vote as seen by its owner
+type VoteLookup struct {
+	ID            string     `json:"id"`
+	EliminationID string     `json:"elimination_id,omitempty"`
+	ParticipantID string     `json:"participant_id,omitempty"`
+	Status        VoteStatus `json:"status"`
+	Code          string     `json:"code,omitempty"`
+	Reason        string     `json:"reason,omitempty"`
+}
+
+// ReceiptVerification is the answer to a receipt verification
+type ReceiptVerification struct {
+	Valid     bool   `json:"valid"`
+	PublicKey string `json:"public_key"`
 }
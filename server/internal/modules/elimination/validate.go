@@ -0,0 +1,49 @@
+package elimination
+
+import (
+	"slices"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+)
+
+// checkVote reports why a vote cast at castAt cannot be counted in the elimination
+// The API runs it before publishing a vote and the consumer runs it again
+// before storing it, so forged or late messages are rejected as well
+func checkVote(elimination Entity, participants []string, vote Vote, castAt time.Time) error {
+	if vote.ID == "" || vote.UserID == "" || vote.ParticipantID == "" {
+		return errs.NewUnprocessableEntityError("vote is missing its voter or participant", nil)
+	}
+	switch elimination.Status {
+	case StatusOpen:
+	case StatusClosing:
+		// Votes cast before the close barrier are still counted while the queue drains
+		if elimination.ClosedAt == nil || !castAt.Before(*elimination.ClosedAt) {
+			return errs.NewForbiddenError("vote was cast after the elimination closed", errs.EliminationNotOpen, nil)
+		}
+	default:
+		return errs.NewForbiddenError("elimination is not open for voting", errs.EliminationNotOpen, nil)
+	}
+	if castAt.Before(elimination.StartDate) || castAt.After(elimination.EndDate) {
+		return errs.NewForbiddenError("vote is outside the voting period", errs.OutsideVotingPeriod, nil)
+	}
+	if !slices.Contains(participants, vote.ParticipantID) {
+		return errs.NewForbiddenError(
+			"participant is not in this elimination",
+			errs.ParticipantNotInElimination,
+			nil,
+		)
+	}
+
+	switch vote.Pool {
+	case PoolFan:
+	case PoolVerified:
+		if elimination.VotingScheme != VotingSchemeDualPool {
+			return errs.NewUnprocessableEntityError("elimination does not accept verified votes", nil)
+		}
+	default:
+		return errs.NewUnprocessableEntityError("invalid vote pool", nil)
+	}
+
+	return nil
+}
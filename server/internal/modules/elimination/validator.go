@@ -0,0 +1,99 @@
+package elimination
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/infra/signer"
+	"github.com/bernardinorafael/globo-challenge/pkg/errs"
+)
+
+// voteValidatorTTL is how long the consumer trusts a cached elimination, so a
+// closed elimination stops accepting votes at most this late
+const voteValidatorTTL = time.Second
+
+type cachedElimination struct {
+	elimination  *Entity
+	participants []string
+	fetched      time.Time
+}
+
+// voteValidator repeats the API vote checks in the consumer
+// Eliminations are cached for a short time since every vote of a batch
+// usually belongs to the same one
+// It is used by the consumer goroutine only, so it is not safe for concurrent use
+type voteValidator struct {
+	eliminationRepo Repository
+	signer          *signer.Signer
+	// unsignedUntil is when votes queued before votes were signed stop being accepted
+	unsignedUntil time.Time
+	cache         map[string]cachedElimination
+}
+
+func newVoteValidator(eliminationRepo Repository, signer *signer.Signer, unsignedUntil time.Time) *voteValidator {
+	return &voteValidator{
+		eliminationRepo: eliminationRepo,
+		signer:          signer,
+		unsignedUntil:   unsignedUntil,
+		cache:           make(map[string]cachedElimination),
+	}
+}
+
+// Check returns an application error when the vote must be rejected, or any
+// other error when the check itself failed and the vote can be retried
+// Only votes signed by the API are accepted, so a message published by anyone
+// else is rejected, a vote queued before votes were signed is only accepted
+// during the configured grace period and is stamped as cast now
+func (v *voteValidator) Check(ctx context.Context, vote *Vote) error {
+	switch {
+	case vote.Signature == "" && time.Now().Before(v.unsignedUntil):
+		vote.Created = time.Now()
+	case vote.Signature == "":
+		return errs.NewUnprocessableEntityError("vote is not signed", nil)
+	case !v.signer.Verify(vote.receipt().Payload(), vote.Signature):
+		return errs.NewUnprocessableEntityError("vote signature is invalid", nil)
+	}
+
+	cached, err := v.get(ctx, vote.EliminationID)
+	if err != nil {
+		return err
+	}
+	if cached.elimination == nil {
+		return errs.NewNotFoundError("elimination not found", nil)
+	}
+
+	return checkVote(*cached.elimination, cached.participants, *vote, vote.Created)
+}
+
+func (v *voteValidator) get(ctx context.Context, eliminationId string) (cachedElimination, error) {
+	now := time.Now()
+	if cached, ok := v.cache[eliminationId]; ok && now.Sub(cached.fetched) < voteValidatorTTL {
+		return cached, nil
+	}
+
+	// Drop the expired entries so closed eliminations do not pile up
+	for id, cached := range v.cache {
+		if now.Sub(cached.fetched) >= voteValidatorTTL {
+			delete(v.cache, id)
+		}
+	}
+
+	cached := cachedElimination{fetched: now}
+
+	elimination, err := v.eliminationRepo.GetByID(ctx, eliminationId)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return cachedElimination{}, err
+	}
+	if elimination != nil {
+		cached.elimination = elimination
+		cached.participants, err = v.eliminationRepo.GetParticipantIDs(ctx, eliminationId)
+		if err != nil {
+			return cachedElimination{}, err
+		}
+	}
+
+	v.cache[eliminationId] = cached
+	return cached, nil
+}
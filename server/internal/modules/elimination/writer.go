@@ -0,0 +1,149 @@
+package elimination
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/metric"
+	"github.com/bernardinorafael/globo-challenge/internal/queue"
+	amqp "github.com/rabbitmq/amqp091-go"
+)
+
+// pendingVote is a decoded vote waiting for its batch to be flushed
+type pendingVote struct {
+	vote     Vote
+	delivery amqp.Delivery
+}
+
+// voteWriter buffers consumed votes and writes them to the database in batches
+// The deliveries of a batch are acked only after the batch is committed, and
+// the votes inserted are counted by the tally
+type voteWriter struct {
+	queue           *queue.Queue
+	eliminationRepo Repository
+	metrics         *metric.Metric
+	tally           *Tally
+	size            int
+	interval        time.Duration
+	pending         []pendingVote
+	oldest          time.Time
+}
+
+func newVoteWriter(
+	queue *queue.Queue,
+	metrics *metric.Metric,
+	eliminationRepo Repository,
+	tally *Tally,
+	size int,
+	interval time.Duration,
+) *voteWriter {
+	return &voteWriter{
+		queue:           queue,
+		eliminationRepo: eliminationRepo,
+		metrics:         metrics,
+		tally:           tally,
+		size:            size,
+		interval:        interval,
+		pending:         make([]pendingVote, 0, size),
+	}
+}
+
+// Add buffers a vote and flushes the batch once it reaches the size threshold
+func (w *voteWriter) Add(ctx context.Context, vote Vote, delivery amqp.Delivery) {
+	if len(w.pending) == 0 {
+		w.oldest = time.Now()
+	}
+	w.pending = append(w.pending, pendingVote{vote: vote, delivery: delivery})
+
+	if len(w.pending) >= w.size {
+		w.Flush(ctx)
+	}
+}
+
+// FlushIfDue flushes the batch once its oldest vote has waited for the flush interval
+func (w *voteWriter) FlushIfDue(ctx context.Context) {
+	if len(w.pending) > 0 && time.Since(w.oldest) >= w.interval {
+		w.Flush(ctx)
+	}
+}
+
+// Flush writes every buffered vote in a single transaction
+func (w *voteWriter) Flush(ctx context.Context) {
+	if len(w.pending) == 0 {
+		return
+	}
+	batch := w.pending
+	w.pending = make([]pendingVote, 0, w.size)
+
+	var timer = w.metrics.ObserveVotingLatency("vote_batch_flush")
+	defer timer.ObserveDuration()
+
+	var votes = make([]Vote, 0, len(batch))
+	for _, p := range batch {
+		votes = append(votes, p.vote)
+	}
+
+	insertion, err := w.eliminationRepo.InsertVotes(ctx, votes)
+	if err != nil {
+		w.metrics.RecordError("database_batch_insert_error")
+		slog.Error("failed to insert vote batch, writing votes one by one", "size", len(batch), "error", err)
+		w.flushEach(ctx, batch)
+		return
+	}
+	w.tally.Add(insertion)
+	rejected := w.reportRejected(insertion)
+
+	// Deliveries come from a single channel in order, so acking the last
+	// delivery with multiple set acks the whole batch at once
+	if err := batch[len(batch)-1].delivery.Ack(true); err != nil {
+		w.metrics.RecordError("queue_ack_error")
+		slog.Error("failed to ack vote batch", "size", len(batch), "error", err)
+	}
+
+	for _, p := range batch {
+		if !rejected[p.vote.ID] {
+			w.metrics.RecordVote(p.vote.ParticipantID, p.vote.Origin)
+		}
+	}
+	slog.Info("vote batch inserted", "size", len(batch))
+}
+
+// flushEach writes the votes of a failed batch one at a time, so a single bad
+// vote does not send the whole batch to the retry queue
+func (w *voteWriter) flushEach(ctx context.Context, batch []pendingVote) {
+	for _, p := range batch {
+		insertion, err := w.eliminationRepo.InsertVote(ctx, p.vote)
+		if err != nil {
+			w.metrics.RecordError("database_insert_error")
+			slog.Error("failed to insert vote", "vote_id", p.vote.ID, "error", err)
+			if err := w.queue.Retry(ctx, queue.VotesQueueName, p.delivery, err); err != nil {
+				w.metrics.RecordError("queue_retry_error")
+				slog.Error("failed to retry vote", "vote_id", p.vote.ID, "error", err)
+			}
+			continue
+		}
+		w.tally.Add(insertion)
+		rejected := w.reportRejected(insertion)
+
+		if err := p.delivery.Ack(false); err != nil {
+			w.metrics.RecordError("queue_ack_error")
+			slog.Error("failed to ack vote", "vote_id", p.vote.ID, "error", err)
+		}
+		if !rejected[p.vote.ID] {
+			w.metrics.RecordVote(p.vote.ParticipantID, p.vote.Origin)
+		}
+	}
+}
+
+// reportRejected logs the votes the database refused while inserting and
+// returns their IDs
+func (w *voteWriter) reportRejected(insertion *Insertion) map[string]bool {
+	var rejected = make(map[string]bool, len(insertion.Rejected))
+	for _, r := range insertion.Rejected {
+		rejected[r.VoteID] = true
+		w.metrics.RecordError("vote_rejected")
+		slog.Warn("vote rejected", "vote_id", r.VoteID, "code", r.Code, "reason", r.Reason)
+	}
+	return rejected
+}
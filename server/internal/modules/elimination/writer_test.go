@@ -0,0 +1,210 @@
+package elimination
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/bernardinorafael/globo-challenge/internal/metric"
+	"github.com/bernardinorafael/globo-challenge/internal/util"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+const (
+	// benchBatchSize and benchTallyInterval match the VOTE_BATCH_SIZE and
+	// VOTE_TALLY_FLUSH_INTERVAL defaults
+	benchBatchSize     = 500
+	benchTallyInterval = time.Second
+)
+
+// The vote write benchmarks run the consumer write path against a real database,
+// given by DB_POSTGRES_DSN, and are skipped without one
+// The per-row path is the single INSERT made for every message before votes
+// were batched, the batch paths are the COPY based writer used by the consumer,
+// alone and with the in-memory tally flushed on its interval
+
+// insertVotePerRow is the vote INSERT the consumer ran for every message
+// before votes were batched
+var insertVotePerRow = `
+	INSERT INTO votes (
+		id,
+		user_id,
+		participant_id,
+		elimination_id,
+		created
+	) VALUES (
+		:id,
+		:user_id,
+		:participant_id,
+		:elimination_id,
+		:created
+	)
+`
+
+func BenchmarkInsertVotePerRow(b *testing.B) {
+	ctx := context.Background()
+	db, _, newVote := setupBench(b)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := db.NamedExecContext(ctx, insertVotePerRow, newVote()); err != nil {
+			b.Fatalf("failed to insert vote: %v", err)
+		}
+	}
+}
+
+func BenchmarkInsertVotesBatch(b *testing.B) {
+	ctx := context.Background()
+	_, repo, newVote := setupBench(b)
+
+	var votes = make([]Vote, 0, benchBatchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		votes = append(votes, newVote())
+		if len(votes) == benchBatchSize || i == b.N-1 {
+			if _, err := repo.InsertVotes(ctx, votes); err != nil {
+				b.Fatalf("failed to insert votes: %v", err)
+			}
+			votes = votes[:0]
+		}
+	}
+}
+
+func BenchmarkInsertVotesTallied(b *testing.B) {
+	ctx := context.Background()
+	_, repo, newVote := setupBench(b)
+
+	tallyCtx, stop := context.WithCancel(ctx)
+	defer stop()
+
+	tally := NewTally(repo, metric.NewMetric(), benchTallyInterval)
+	tally.Start(tallyCtx)
+
+	var votes = make([]Vote, 0, benchBatchSize)
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		votes = append(votes, newVote())
+		if len(votes) == benchBatchSize || i == b.N-1 {
+			insertion, err := repo.InsertVotes(ctx, votes)
+			if err != nil {
+				b.Fatalf("failed to insert votes: %v", err)
+			}
+			tally.Add(insertion)
+			votes = votes[:0]
+		}
+	}
+
+	// The last flush is part of the cost, every vote must reach the counters
+	if err := tally.Flush(ctx); err != nil {
+		b.Fatalf("failed to flush tally: %v", err)
+	}
+}
+
+// setupBench connects to the benchmark database and creates the fixtures the
+// votes are cast for, which are removed once the benchmark ends
+func setupBench(b *testing.B) (*sqlx.DB, Repository, func() Vote) {
+	b.Helper()
+
+	dsn := os.Getenv("DB_POSTGRES_DSN")
+	if dsn == "" {
+		b.Skip("DB_POSTGRES_DSN is not set")
+	}
+
+	db, err := sqlx.Open("postgres", dsn)
+	if err != nil {
+		b.Fatalf("failed to open database: %v", err)
+	}
+	b.Cleanup(func() { db.Close() })
+
+	ctx := context.Background()
+	userId, participantId, eliminationId, err := createBenchFixtures(ctx, db)
+	if err != nil {
+		b.Fatalf("failed to create benchmark fixtures: %v", err)
+	}
+	b.Cleanup(func() {
+		if err := deleteBenchFixtures(ctx, db, userId, participantId, eliminationId); err != nil {
+			b.Errorf("failed to delete benchmark fixtures: %v", err)
+		}
+	})
+
+	newVote := func() Vote {
+		return Vote{
+			ID:            util.GenID("vote"),
+			UserID:        userId,
+			EliminationID: eliminationId,
+			ParticipantID: participantId,
+			Created:       time.Now(),
+		}
+	}
+
+	return db, NewRepository(db), newVote
+}
+
+func createBenchFixtures(ctx context.Context, db *sqlx.DB) (string, string, string, error) {
+	userId := util.GenID("user")
+	participantId := util.GenID("partic")
+	eliminationId := util.GenID("elim")
+
+	err := util.ExecTx(ctx, db, func(tx *sqlx.Tx) error {
+		_, err := tx.ExecContext(
+			ctx,
+			"INSERT INTO users (id, name, email, password) VALUES ($1, 'benchmark', $2, '-')",
+			userId,
+			userId+"@benchmark.local",
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert user: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO eliminations (id, start_date, end_date) VALUES ($1, now(), now())",
+			eliminationId,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert elimination: %w", err)
+		}
+
+		_, err = tx.ExecContext(
+			ctx,
+			"INSERT INTO participants (id, name) VALUES ($1, $1)",
+			participantId,
+		)
+		if err != nil {
+			return fmt.Errorf("failed to insert participant: %w", err)
+		}
+
+		return nil
+	})
+
+	return userId, participantId, eliminationId, err
+}
+
+// deleteBenchFixtures removes the fixtures, the votes, their ledger and counters are removed by cascade
+// The ledger is append-only, so its trigger is disabled for this transaction
+// only, which needs the benchmark to run as the owner of the table
+func deleteBenchFixtures(ctx context.Context, db *sqlx.DB, userId, participantId, eliminationId string) error {
+	return util.ExecTx(ctx, db, func(tx *sqlx.Tx) error {
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE vote_ledger DISABLE TRIGGER vote_ledger_append_only"); err != nil {
+			return fmt.Errorf("failed to disable ledger trigger: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM eliminations WHERE id = $1", eliminationId); err != nil {
+			return fmt.Errorf("failed to delete elimination: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "ALTER TABLE vote_ledger ENABLE TRIGGER vote_ledger_append_only"); err != nil {
+			return fmt.Errorf("failed to enable ledger trigger: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM participants WHERE id = $1", participantId); err != nil {
+			return fmt.Errorf("failed to delete participant: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, "DELETE FROM users WHERE id = $1", userId); err != nil {
+			return fmt.Errorf("failed to delete user: %w", err)
+		}
+		return nil
+	})
+}
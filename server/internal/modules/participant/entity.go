@@ -14,34 +14,34 @@ const (
 
 // participant is the internal representation of the participant entity
 type participant struct {
-	id            string
-	name          string
-	picture       *string
-	eliminationID *string
-	created       time.Time
-	updated       time.Time
+	id      string
+	name    string
+	picture *string
+	status  Status
+	created time.Time
+	updated time.Time
 }
 
 func NewParticipantFromDatabase(entity Entity) (*participant, error) {
 	return &participant{
-		id:            entity.ID,
-		name:          entity.Name,
-		picture:       entity.Picture,
-		eliminationID: entity.EliminationID,
-		created:       entity.Created,
-		updated:       entity.Updated,
+		id:      entity.ID,
+		name:    entity.Name,
+		picture: entity.Picture,
+		status:  entity.Status,
+		created: entity.Created,
+		updated: entity.Updated,
 	}, nil
 }
 
 // NewParticipant creates a new participant entity
 func NewParticipant(name string) (*participant, error) {
 	p := participant{
-		id:            util.GenID("partic"),
-		name:          name,
-		picture:       nil,
-		eliminationID: nil,
-		created:       time.Now(),
-		updated:       time.Now(),
+		id:      util.GenID("partic"),
+		name:    name,
+		picture: nil,
+		status:  StatusActive,
+		created: time.Now(),
+		updated: time.Now(),
 	}
 
 	if err := p.validate(); err != nil {
@@ -64,26 +64,40 @@ func (p *participant) validate() error {
 	return nil
 }
 
-func (p *participant) SetElimination(eliminationID string) {
-	p.eliminationID = &eliminationID
+// Withdraw marks the participant as having left the house
+func (p *participant) Withdraw() error {
+	if p.status == StatusEliminated {
+		return errors.New("participant was already eliminated")
+	}
+	if p.status == StatusInElimination {
+		return errors.New("participant is in an elimination")
+	}
+
+	p.status = StatusWithdrawn
 	p.updated = time.Now()
+	return nil
+}
+
+// CanJoinElimination reports whether the participant can be put up for elimination
+func (p *participant) CanJoinElimination() bool {
+	return p.status != StatusEliminated && p.status != StatusWithdrawn
 }
 
 // Store returns the participant entity in a format that can be stored in the database
 func (p *participant) Store() Entity {
 	return Entity{
-		ID:            p.id,
-		Name:          p.name,
-		Picture:       p.picture,
-		EliminationID: p.eliminationID,
-		Created:       p.created,
-		Updated:       p.updated,
+		ID:      p.id,
+		Name:    p.name,
+		Picture: p.picture,
+		Status:  p.status,
+		Created: p.created,
+		Updated: p.updated,
 	}
 }
 
-func (p *participant) ID() string             { return p.id }
-func (p *participant) Name() string           { return p.name }
-func (p *participant) Picture() *string       { return p.picture }
-func (p *participant) EliminationID() *string { return p.eliminationID }
-func (p *participant) Created() time.Time     { return p.created }
-func (p *participant) Updated() time.Time     { return p.updated }
+func (p *participant) ID() string         { return p.id }
+func (p *participant) Name() string       { return p.name }
+func (p *participant) Picture() *string   { return p.picture }
+func (p *participant) Status() Status     { return p.status }
+func (p *participant) Created() time.Time { return p.created }
+func (p *participant) Updated() time.Time { return p.updated }
@@ -31,7 +31,7 @@ func (r repository) Update(ctx context.Context, participant Entity) error {
 		UPDATE participants SET
 				name = :name,
 				picture = :picture,
-				elimination_id = :elimination_id,
+				status = :status,
 				updated = :updated
 			WHERE id = :id`,
 		participant,
@@ -43,6 +43,39 @@ func (r repository) Update(ctx context.Context, participant Entity) error {
 	return nil
 }
 
+// errStatusChanged is returned when a participant entered or left an
+// elimination after a change was decided
+var errStatusChanged = errors.New("participant status changed")
+
+// Withdraw stores the withdrawal of a participant, only while the participant
+// is not in an elimination nor eliminated, so an elimination created in the
+// meantime is never left with a withdrawn participant
+func (r repository) Withdraw(ctx context.Context, participant Entity) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		UPDATE participants SET
+			status = :status,
+			updated = :updated
+		WHERE id = :id AND status NOT IN ('in_elimination', 'eliminated')
+	`
+
+	res, err := r.db.NamedExecContext(ctx, query, participant)
+	if err != nil {
+		return fmt.Errorf("failed to withdraw participant: %w", err)
+	}
+	affected, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("failed to get rows affected: %w", err)
+	}
+	if affected == 0 {
+		return errStatusChanged
+	}
+
+	return nil
+}
+
 func (r repository) Delete(ctx context.Context, participantId string) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
@@ -55,12 +88,19 @@ func (r repository) Delete(ctx context.Context, participantId string) error {
 	return nil
 }
 
-func (r repository) GetAll(ctx context.Context) ([]Entity, error) {
+// GetAll returns every participant, or only the ones in the given status when it is not empty
+func (r repository) GetAll(ctx context.Context, status Status) ([]Entity, error) {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
 
+	var query = `
+		SELECT * FROM participants
+		WHERE $1 = '' OR status = $1
+		ORDER BY created DESC
+	`
+
 	var participants = []Entity{}
-	err := r.db.SelectContext(ctx, &participants, "SELECT * FROM participants ORDER BY created DESC")
+	err := r.db.SelectContext(ctx, &participants, query, status)
 	if err != nil {
 		log.Println("failed to get all participants: %w", err)
 		return nil, fmt.Errorf("failed to get all participants: %w", err)
@@ -110,14 +150,14 @@ func (r repository) Insert(ctx context.Context, participant Entity) error {
 			id,
 			name,
 			picture,
-			elimination_id,
+			status,
 			created,
 			updated
 		) VALUES (
 			:id,
 			:name,
 			:picture,
-			:elimination_id,
+			:status,
 			:created,
 			:updated
 		)
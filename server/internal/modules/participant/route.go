@@ -40,13 +40,16 @@ func (c controller) RegisterRoutes(r *chi.Mux) {
 		r.Post("/", c.handleCreateParticipant)
 		r.Get("/", c.handleGetAllParticipants)
 		r.Delete("/{participantId}", c.handleDeleteParticipant)
+		r.With(m.WithAdmin).Patch("/{participantId}/withdraw", c.handleWithdrawParticipant)
 	})
 }
 
 func (c controller) handleGetAllParticipants(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
-	participants, err := c.participantService.GetAllParticipants(ctx)
+	status := Status(r.URL.Query().Get("status"))
+
+	participants, err := c.participantService.GetAllParticipants(ctx, status)
 	if err != nil {
 		errs.HttpError(w, err)
 		return
@@ -86,3 +89,15 @@ func (c controller) handleDeleteParticipant(w http.ResponseWriter, r *http.Reque
 
 	util.WriteSuccess(w, http.StatusOK)
 }
+
+func (c controller) handleWithdrawParticipant(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	err := c.participantService.WithdrawParticipant(ctx, chi.URLParam(r, "participantId"))
+	if err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteSuccess(w, http.StatusOK)
+}
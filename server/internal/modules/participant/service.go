@@ -2,6 +2,7 @@ package participant
 
 import (
 	"context"
+	"errors"
 
 	"github.com/bernardinorafael/globo-challenge/pkg/errs"
 )
@@ -18,45 +19,66 @@ func NewService(ctx context.Context, partipantRepo Repository) Service {
 	}
 }
 
-func (s *service) AssignElimination(ctx context.Context, participantId string, eliminationId string) error {
+func (s *service) DeleteParticipant(ctx context.Context, participantId string) error {
 	record, err := s.partipantRepo.GetByID(ctx, participantId)
 	if err != nil {
 		return errs.NewBadRequestError("failed to get participant by id", err)
 	}
-
-	participant, err := NewParticipantFromDatabase(*record)
-	if err != nil {
-		return errs.NewBadRequestError("failed to create participant from database", err)
+	if record == nil {
+		return errs.NewNotFoundError("participant not found", err)
 	}
-	participant.SetElimination(eliminationId)
 
-	err = s.partipantRepo.Update(ctx, participant.Store())
+	err = s.partipantRepo.Delete(ctx, participantId)
 	if err != nil {
-		return errs.NewBadRequestError("failed to update participant", err)
+		return errs.NewBadRequestError("failed to delete participant", err)
 	}
 
 	return nil
 }
 
-func (s *service) DeleteParticipant(ctx context.Context, participantId string) error {
+func (s *service) GetParticipant(ctx context.Context, participantId string) (*Entity, error) {
 	record, err := s.partipantRepo.GetByID(ctx, participantId)
 	if err != nil {
-		return errs.NewBadRequestError("failed to get participant by id", err)
+		return nil, errs.NewBadRequestError("failed to get participant by id", err)
 	}
 	if record == nil {
-		return errs.NewNotFoundError("participant not found", err)
+		return nil, errs.NewNotFoundError("participant not found", nil)
 	}
 
-	err = s.partipantRepo.Delete(ctx, participantId)
+	return record, nil
+}
+
+func (s *service) WithdrawParticipant(ctx context.Context, participantId string) error {
+	record, err := s.GetParticipant(ctx, participantId)
 	if err != nil {
-		return errs.NewBadRequestError("failed to delete participant", err)
+		return err
+	}
+
+	participant, err := NewParticipantFromDatabase(*record)
+	if err != nil {
+		return errs.NewBadRequestError("failed to create participant from database", err)
+	}
+	if err := participant.Withdraw(); err != nil {
+		return errs.NewForbiddenError(err.Error(), errs.InvalidState, err)
+	}
+
+	err = s.partipantRepo.Withdraw(ctx, participant.Store())
+	if err != nil {
+		if errors.Is(err, errStatusChanged) {
+			return errs.NewConflictError("participant changed status while withdrawing, try again", err)
+		}
+		return errs.NewBadRequestError("failed to update participant", err)
 	}
 
 	return nil
 }
 
-func (s *service) GetAllParticipants(ctx context.Context) ([]Entity, error) {
-	participants, err := s.partipantRepo.GetAll(ctx)
+func (s *service) GetAllParticipants(ctx context.Context, status Status) ([]Entity, error) {
+	if status != "" && !status.IsValid() {
+		return nil, errs.NewUnprocessableEntityError("invalid participant status", nil)
+	}
+
+	participants, err := s.partipantRepo.GetAll(ctx, status)
 	if err != nil {
 		return nil, errs.NewBadRequestError("failed to get all participants", err)
 	}
@@ -65,7 +87,7 @@ func (s *service) GetAllParticipants(ctx context.Context) ([]Entity, error) {
 }
 
 func (s *service) CreateParticipant(ctx context.Context, name string) error {
-	participants, err := s.GetAllParticipants(ctx)
+	participants, err := s.GetAllParticipants(ctx, "")
 	if err != nil {
 		return errs.NewBadRequestError("failed to get all participants", err)
 	}
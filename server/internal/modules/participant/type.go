@@ -2,11 +2,35 @@ package participant
 
 import "time"
 
+// Status is the lifecycle state of a participant
+type Status string
+
+const (
+	// StatusActive is a participant in the house and not in an elimination
+	StatusActive Status = "active"
+	// StatusInElimination is a participant up for elimination in a poll that is not finished
+	StatusInElimination Status = "in_elimination"
+	// StatusEliminated is a participant voted out of the house
+	StatusEliminated Status = "eliminated"
+	// StatusWithdrawn is a participant who left the house
+	StatusWithdrawn Status = "withdrawn"
+)
+
+// IsValid reports whether s is a known participant status
+func (s Status) IsValid() bool {
+	switch s {
+	case StatusActive, StatusInElimination, StatusEliminated, StatusWithdrawn:
+		return true
+	default:
+		return false
+	}
+}
+
 type Entity struct {
-	ID            string    `json:"id" db:"id"`
-	Name          string    `json:"name" db:"name"`
-	Picture       *string   `json:"picture" db:"picture"`
-	EliminationID *string   `json:"elimination_id" db:"elimination_id"`
-	Created       time.Time `json:"created" db:"created"`
-	Updated       time.Time `json:"updated" db:"updated"`
+	ID      string    `json:"id" db:"id"`
+	Name    string    `json:"name" db:"name"`
+	Picture *string   `json:"picture" db:"picture"`
+	Status  Status    `json:"status" db:"status"`
+	Created time.Time `json:"created" db:"created"`
+	Updated time.Time `json:"updated" db:"updated"`
 }
@@ -6,6 +6,7 @@ import (
 	"regexp"
 	"time"
 
+	"github.com/bernardinorafael/globo-challenge/internal/infra/token"
 	"github.com/bernardinorafael/globo-challenge/internal/util"
 	"github.com/bernardinorafael/globo-challenge/pkg/crypto"
 )
@@ -21,6 +22,8 @@ type user struct {
 	name     string
 	email    string
 	password string
+	verified bool
+	role     string
 	created  time.Time
 	updated  time.Time
 }
@@ -32,6 +35,8 @@ func NewUserFromDatabase(entity Entity) (*user, error) {
 		name:     entity.Name,
 		email:    entity.Email,
 		password: entity.Password,
+		verified: entity.Verified,
+		role:     entity.Role,
 		created:  entity.Created,
 		updated:  entity.Updated,
 	}
@@ -50,6 +55,7 @@ func NewUser(name, email, password string) (*user, error) {
 		name:     name,
 		email:    email,
 		password: password,
+		role:     token.RoleUser,
 		created:  time.Now(),
 		updated:  time.Now(),
 	}
@@ -85,6 +91,12 @@ func (u *user) validate() error {
 	return nil
 }
 
+// Verify marks the user identity as verified
+func (u *user) Verify() {
+	u.verified = true
+	u.updated = time.Now()
+}
+
 func (u *user) ComparePassword(password string) bool {
 	return crypto.PasswordMatches(password, u.password)
 }
@@ -106,6 +118,8 @@ func (u *user) Store() Entity {
 		Name:     u.name,
 		Email:    u.email,
 		Password: u.password,
+		Verified: u.verified,
+		Role:     u.role,
 		Created:  u.created,
 		Updated:  u.updated,
 	}
@@ -115,5 +129,7 @@ func (u *user) ID() string         { return u.id }
 func (u *user) Name() string       { return u.name }
 func (u *user) Email() string      { return u.email }
 func (u *user) Password() string   { return u.password }
+func (u *user) Verified() bool     { return u.verified }
+func (u *user) Role() string       { return u.role }
 func (u *user) Created() time.Time { return u.created }
 func (u *user) Updated() time.Time { return u.updated }
@@ -9,6 +9,7 @@ import (
 type Repository interface {
 	Insert(ctx context.Context, user Entity) error
 	Delete(ctx context.Context, userId string) error
+	Update(ctx context.Context, user Entity) error
 	GetByID(ctx context.Context, userId string) (*Entity, error)
 	GetByEmail(ctx context.Context, email string) (*Entity, error)
 }
@@ -17,4 +18,5 @@ type Service interface {
 	Register(ctx context.Context, input dto.Register) error
 	Login(ctx context.Context, input dto.Login) (*dto.LoginResponse, error)
 	GetSignedUser(ctx context.Context, userId string) (*dto.UserResponse, error)
+	VerifyUser(ctx context.Context, userId string) error
 }
@@ -50,6 +50,28 @@ func (r repository) Insert(ctx context.Context, user Entity) error {
 	return nil
 }
 
+func (r repository) Update(ctx context.Context, user Entity) error {
+	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
+	defer cancel()
+
+	var query = `
+		UPDATE users SET
+			name = :name,
+			email = :email,
+			password = :password,
+			verified = :verified,
+			updated = :updated
+		WHERE id = :id
+	`
+
+	_, err := r.db.NamedExecContext(ctx, query, user)
+	if err != nil {
+		return fmt.Errorf("failed to update user: %w", err)
+	}
+
+	return nil
+}
+
 func (r repository) Delete(ctx context.Context, userId string) error {
 	ctx, cancel := context.WithTimeout(ctx, 2*time.Second)
 	defer cancel()
@@ -45,9 +45,22 @@ func (c controller) RegisterRoutes(r *chi.Mux) {
 	r.Route(basePath+"users", func(r chi.Router) {
 		r.Use(m.WithAuth)
 		r.Get("/me", c.handleGetSignedUrl)
+		// Verifying an identity is an admin decision, never one a user makes for themselves
+		r.With(m.WithAdmin).Patch("/{userId}/verify", c.handleVerifyUser)
 	})
 }
 
+func (c controller) handleVerifyUser(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+
+	if err := c.userService.VerifyUser(ctx, chi.URLParam(r, "userId")); err != nil {
+		errs.HttpError(w, err)
+		return
+	}
+
+	util.WriteSuccess(w, http.StatusOK)
+}
+
 func (c controller) handleGetSignedUrl(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
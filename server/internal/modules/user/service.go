@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/bernardinorafael/globo-challenge/internal/infra/client"
 	"github.com/bernardinorafael/globo-challenge/internal/infra/token"
 	"github.com/bernardinorafael/globo-challenge/internal/shared/dto"
 	"github.com/bernardinorafael/globo-challenge/internal/util"
@@ -17,13 +18,15 @@ type service struct {
 	ctx       context.Context
 	userRepo  Repository
 	secretKey string
+	clients   *client.Registry
 }
 
-func NewService(ctx context.Context, userRepo Repository, secretKey string) Service {
+func NewService(ctx context.Context, userRepo Repository, secretKey string, clients *client.Registry) Service {
 	return &service{
 		ctx:       ctx,
 		userRepo:  userRepo,
 		secretKey: secretKey,
+		clients:   clients,
 	}
 }
 
@@ -37,16 +40,41 @@ func (s *service) GetSignedUser(ctx context.Context, userId string) (*dto.UserRe
 	}
 
 	user := dto.UserResponse{
-		ID:      record.ID,
-		Name:    record.Name,
-		Email:   record.Email,
-		Created: record.Created,
-		Updated: record.Updated,
+		ID:       record.ID,
+		Name:     record.Name,
+		Email:    record.Email,
+		Verified: record.Verified,
+		Role:     record.Role,
+		Created:  record.Created,
+		Updated:  record.Updated,
 	}
 
 	return &user, nil
 }
 
+func (s *service) VerifyUser(ctx context.Context, userId string) error {
+	record, err := s.userRepo.GetByID(ctx, userId)
+	if err != nil {
+		return errs.NewBadRequestError("failed to retrieve user", err)
+	}
+	if record == nil {
+		return errs.NewNotFoundError("user not found", nil)
+	}
+
+	user, err := NewUserFromDatabase(*record)
+	if err != nil {
+		return errs.NewBadRequestError("failed to create user", err)
+	}
+	user.Verify()
+
+	err = s.userRepo.Update(ctx, user.Store())
+	if err != nil {
+		return errs.NewBadRequestError("failed to update user", err)
+	}
+
+	return nil
+}
+
 func (s *service) Register(ctx context.Context, input dto.Register) error {
 	// TODO: Implement a `Fields` property in the errors struct
 	// to return the fields that are invalid
@@ -73,6 +101,11 @@ func (s *service) Register(ctx context.Context, input dto.Register) error {
 }
 
 func (s *service) Login(ctx context.Context, input dto.Login) (*dto.LoginResponse, error) {
+	channel, err := s.clients.Authenticate(input.ClientID, input.ClientSecret)
+	if err != nil {
+		return nil, errs.NewForbiddenError("client credentials are incorrect", errs.InvalidCredentials, err)
+	}
+
 	record, err := s.userRepo.GetByEmail(ctx, input.Email)
 	if err != nil {
 		return nil, errs.NewBadRequestError("failed to retrieve user", err)
@@ -98,7 +131,15 @@ func (s *service) Login(ctx context.Context, input dto.Login) (*dto.LoginRespons
 		)
 	}
 
-	accessToken, claims, err := token.Generate(s.secretKey, user.ID(), user.Email(), time.Hour*24)
+	accessToken, claims, err := token.Generate(
+		s.secretKey,
+		user.ID(),
+		user.Email(),
+		string(channel),
+		user.Role(),
+		user.Verified(),
+		time.Hour*24,
+	)
 	if err != nil {
 		return nil, errs.NewBadRequestError("failed to generate token", err)
 	}
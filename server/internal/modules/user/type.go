@@ -3,10 +3,14 @@ package user
 import "time"
 
 type Entity struct {
-	ID       string    `json:"id" db:"id"`
-	Name     string    `json:"name" db:"name"`
-	Email    string    `json:"email" db:"email"`
-	Password string    `json:"password" db:"password"`
-	Created  time.Time `json:"created" db:"created"`
-	Updated  time.Time `json:"updated" db:"updated"`
+	ID       string `json:"id" db:"id"`
+	Name     string `json:"name" db:"name"`
+	Email    string `json:"email" db:"email"`
+	Password string `json:"password" db:"password"`
+	// Verified users can cast the single verified vote of dual-pool eliminations
+	Verified bool `json:"verified" db:"verified"`
+	// Role is set in the database, new users are created with the user role
+	Role    string    `json:"role" db:"role"`
+	Created time.Time `json:"created" db:"created"`
+	Updated time.Time `json:"updated" db:"updated"`
 }
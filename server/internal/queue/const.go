@@ -1,12 +1,72 @@
 package queue
 
+import (
+	"fmt"
+	"time"
+)
+
 const (
 	// MainExchangeName defines the central distribution point for BBB voting events
 	// Format: <system>-<domain>-<type>
 	MainExchangeName = "bbb_voting_events"
+	// RetryExchangeName defines the exchange that routes failed messages to their delayed retry queues
+	RetryExchangeName = "bbb_voting_retry"
 	// VotesCreatedKey defines the routing pattern for vote messages
 	// Format: <entity>.<action>.<event>
 	VotesCreatedKey = "votes.submission.created"
 	// VotesQueueName defines the name of the votes queue
 	VotesQueueName = "votes_queue"
+	// BarrierMessageType marks a message that is not a vote but the point in the
+	// votes queue up to which a closed elimination must be drained
+	BarrierMessageType = "barrier"
+)
+
+const (
+	// retryCountHeader stores how many times a message has already been retried
+	retryCountHeader = "x-retry-count"
+	// errorHeader stores the last processing error of a dead-lettered message
+	errorHeader = "x-error"
+	// deadLetteredAtHeader stores when the message was moved to the dead-letter queue
+	deadLetteredAtHeader = "x-dead-lettered-at"
 )
+
+// RetryDelays defines the bounded exponential backoff applied to failed messages
+// A message is dead-lettered once it has been retried len(RetryDelays) times
+var RetryDelays = []time.Duration{
+	time.Second * 1,
+	time.Second * 5,
+	time.Second * 25,
+}
+
+// RetryWindow returns the longest a message waits in the retry queues before
+// its last attempt
+func RetryWindow() time.Duration {
+	var window time.Duration
+	for _, delay := range RetryDelays {
+		window += delay
+	}
+	return window
+}
+
+// Queues lists every work queue declared by the application
+var Queues = []string{VotesQueueName}
+
+// IsWorkQueue reports whether name is one of the declared work queues
+func IsWorkQueue(name string) bool {
+	for _, queue := range Queues {
+		if queue == name {
+			return true
+		}
+	}
+	return false
+}
+
+// DeadLetterQueueName returns the name of the dead-letter queue of a work queue
+func DeadLetterQueueName(name string) string {
+	return name + ".dlq"
+}
+
+// RetryQueueName returns the name of the retry queue used for the given attempt
+func RetryQueueName(name string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", name, attempt)
+}
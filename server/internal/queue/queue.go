@@ -2,7 +2,10 @@ package queue
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sync"
+	"time"
 
 	amqp "github.com/rabbitmq/amqp091-go"
 )
@@ -10,7 +13,35 @@ import (
 type Queue struct {
 	conn    *amqp.Connection
 	channel *amqp.Channel
-	uri     string
+	// publisher is a dedicated channel in confirm mode, so every publish
+	// can wait for the broker to acknowledge the message
+	publisher *amqp.Channel
+	// retrier is a confirm channel for the messages moved to the retry and
+	// dead-letter queues, published as mandatory so a message no queue takes is
+	// reported instead of dropped
+	// Its publishes are serialized, so a return always belongs to the message
+	// being confirmed
+	retrier   *amqp.Channel
+	returns   chan amqp.Return
+	retrierMu sync.Mutex
+	uri       string
+}
+
+// QueueInfo describes the current state of a declared queue
+type QueueInfo struct {
+	Name      string `json:"name"`
+	Messages  int    `json:"messages"`
+	Consumers int    `json:"consumers"`
+}
+
+// DeadLetter describes a message stored in a dead-letter queue
+type DeadLetter struct {
+	MessageID      string    `json:"message_id"`
+	RoutingKey     string    `json:"routing_key"`
+	Retries        int       `json:"retries"`
+	Error          string    `json:"error"`
+	DeadLetteredAt time.Time `json:"dead_lettered_at"`
+	Body           string    `json:"body"`
 }
 
 func New(uri string) (*Queue, error) {
@@ -24,7 +55,33 @@ func New(uri string) (*Queue, error) {
 		return nil, fmt.Errorf("failed to create channel: %w", err)
 	}
 
-	queue := &Queue{conn: conn, channel: ch, uri: uri}
+	publisher, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create publisher channel: %w", err)
+	}
+	if err := publisher.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to put publisher channel in confirm mode: %w", err)
+	}
+
+	retrier, err := conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create retrier channel: %w", err)
+	}
+	if err := retrier.Confirm(false); err != nil {
+		return nil, fmt.Errorf("failed to put retrier channel in confirm mode: %w", err)
+	}
+	// The broker sends a return before the ack of the same message, the buffer
+	// keeps the channel from blocking on returns nobody is waiting for
+	returns := retrier.NotifyReturn(make(chan amqp.Return, 16))
+
+	queue := &Queue{
+		conn:      conn,
+		channel:   ch,
+		publisher: publisher,
+		retrier:   retrier,
+		returns:   returns,
+		uri:       uri,
+	}
 
 	if err := queue.declareExchange(); err != nil {
 		return nil, fmt.Errorf("failed to declare exchange: %w", err)
@@ -39,25 +96,43 @@ func New(uri string) (*Queue, error) {
 	return queue, nil
 }
 
-func (q *Queue) Publish(ctx context.Context, key string, message []byte) error {
-	return q.channel.PublishWithContext(
-		ctx,
-		MainExchangeName, // exchange
-		key,              // routing key
-		false,            // mandatory
-		false,            // immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        message,
-		},
-	)
+// Publish sends a message to the main exchange and blocks until the broker confirms it
+// The message ID lets consumers recognize redelivered messages
+func (q *Queue) Publish(ctx context.Context, key, messageID string, message []byte) error {
+	return q.publish(ctx, MainExchangeName, key, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Timestamp:    time.Now(),
+		Body:         message,
+	})
 }
 
-func (q *Queue) Consume(name string) (<-chan amqp.Delivery, error) {
+// PublishBarrier sends a barrier message through the main exchange, so it is
+// queued behind every message already published with the same key
+func (q *Queue) PublishBarrier(ctx context.Context, key, messageID string, message []byte) error {
+	return q.publish(ctx, MainExchangeName, key, amqp.Publishing{
+		ContentType:  "application/json",
+		DeliveryMode: amqp.Persistent,
+		MessageId:    messageID,
+		Type:         BarrierMessageType,
+		Timestamp:    time.Now(),
+		Body:         message,
+	})
+}
+
+// Consume starts delivering messages from the given queue, holding at most
+// prefetch unacked messages at once
+// Every delivery must be acknowledged by the caller, either with Ack or through Retry/DeadLetter
+func (q *Queue) Consume(name string, prefetch int) (<-chan amqp.Delivery, error) {
+	if err := q.channel.Qos(prefetch, 0, false); err != nil {
+		return nil, fmt.Errorf("failed to set channel qos: %w", err)
+	}
+
 	return q.channel.Consume(
 		name,  // queue
 		"",    // consumer
-		true,  // auto-ack
+		false, // auto-ack
 		false, // exclusive
 		false, // no-local
 		false, // no-wait
@@ -65,7 +140,164 @@ func (q *Queue) Consume(name string) (<-chan amqp.Delivery, error) {
 	)
 }
 
+// Retry schedules a failed delivery on the next retry queue of the given queue
+// When every retry attempt has already been used the delivery is dead-lettered instead
+// The delivery is acked only once the broker confirms the copy was queued, and
+// is requeued when it was not
+func (q *Queue) Retry(ctx context.Context, name string, d amqp.Delivery, cause error) error {
+	attempt := retryCount(d.Headers) + 1
+	if attempt > len(RetryDelays) {
+		return q.DeadLetter(ctx, name, d, cause)
+	}
+
+	headers := copyHeaders(d.Headers)
+	headers[retryCountHeader] = int32(attempt)
+	headers[errorHeader] = cause.Error()
+
+	err := q.publishMandatory(ctx, RetryExchangeName, RetryQueueName(name, attempt), republish(d, headers))
+	if err != nil {
+		_ = d.Nack(false, true)
+		return fmt.Errorf("failed to publish message to retry queue: %w", err)
+	}
+
+	return d.Ack(false)
+}
+
+// DeadLetter moves a delivery to the dead-letter queue of the given queue
+func (q *Queue) DeadLetter(ctx context.Context, name string, d amqp.Delivery, cause error) error {
+	headers := copyHeaders(d.Headers)
+	headers[errorHeader] = cause.Error()
+	headers[deadLetteredAtHeader] = time.Now()
+
+	err := q.publishMandatory(ctx, "", DeadLetterQueueName(name), republish(d, headers))
+	if err != nil {
+		_ = d.Nack(false, true)
+		return fmt.Errorf("failed to publish message to dead-letter queue: %w", err)
+	}
+
+	return d.Ack(false)
+}
+
+// Inspect returns the message and consumer count of the given queue and its
+// retry and dead-letter queues
+func (q *Queue) Inspect(name string) ([]QueueInfo, error) {
+	names := []string{name}
+	for attempt := range RetryDelays {
+		names = append(names, RetryQueueName(name, attempt+1))
+	}
+	names = append(names, DeadLetterQueueName(name))
+
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+	defer ch.Close()
+
+	var infos []QueueInfo
+	for _, n := range names {
+		state, err := ch.QueueDeclarePassive(n, true, false, false, false, nil)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect queue %s: %w", n, err)
+		}
+		infos = append(infos, QueueInfo{
+			Name:      state.Name,
+			Messages:  state.Messages,
+			Consumers: state.Consumers,
+		})
+	}
+
+	return infos, nil
+}
+
+// PeekDeadLetters returns up to limit messages of the dead-letter queue of the given queue
+// The messages are left in the queue
+func (q *Queue) PeekDeadLetters(name string, limit int) ([]DeadLetter, error) {
+	// Messages fetched without ack go back to the queue when the channel is closed
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create channel: %w", err)
+	}
+	defer ch.Close()
+
+	var letters = []DeadLetter{}
+	for len(letters) < limit {
+		d, ok, err := ch.Get(DeadLetterQueueName(name), false)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get dead-lettered message: %w", err)
+		}
+		if !ok {
+			break
+		}
+		letters = append(letters, newDeadLetter(d))
+	}
+
+	return letters, nil
+}
+
+// RequeueDeadLetters moves up to limit messages of the dead-letter queue back
+// to the given queue with a fresh retry budget, returning how many were moved
+func (q *Queue) RequeueDeadLetters(ctx context.Context, name string, limit int) (int, error) {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create channel: %w", err)
+	}
+	defer ch.Close()
+
+	var requeued int
+	for requeued < limit {
+		d, ok, err := ch.Get(DeadLetterQueueName(name), false)
+		if err != nil {
+			return requeued, fmt.Errorf("failed to get dead-lettered message: %w", err)
+		}
+		if !ok {
+			break
+		}
+
+		headers := copyHeaders(d.Headers)
+		delete(headers, retryCountHeader)
+		delete(headers, errorHeader)
+		delete(headers, deadLetteredAtHeader)
+
+		err = q.publishMandatory(ctx, "", name, republish(d, headers))
+		if err != nil {
+			_ = d.Nack(false, true)
+			return requeued, fmt.Errorf("failed to requeue dead-lettered message: %w", err)
+		}
+		if err := d.Ack(false); err != nil {
+			return requeued, fmt.Errorf("failed to ack dead-lettered message: %w", err)
+		}
+		requeued++
+	}
+
+	return requeued, nil
+}
+
+// PurgeDeadLetters removes every message of the dead-letter queue of the given
+// queue, returning how many were removed
+func (q *Queue) PurgeDeadLetters(name string) (int, error) {
+	ch, err := q.conn.Channel()
+	if err != nil {
+		return 0, fmt.Errorf("failed to create channel: %w", err)
+	}
+	defer ch.Close()
+
+	count, err := ch.QueuePurge(DeadLetterQueueName(name), false)
+	if err != nil {
+		return 0, fmt.Errorf("failed to purge dead-letter queue: %w", err)
+	}
+
+	return count, nil
+}
+
 func (q *Queue) Close() error {
+	if err := q.publisher.Close(); err != nil {
+		return fmt.Errorf("failed to close publisher channel: %w", err)
+	}
+
+	if err := q.retrier.Close(); err != nil {
+		return fmt.Errorf("failed to close retrier channel: %w", err)
+	}
+
 	if err := q.channel.Close(); err != nil {
 		return fmt.Errorf("failed to close channel: %w", err)
 	}
@@ -77,28 +309,132 @@ func (q *Queue) Close() error {
 	return nil
 }
 
+// publish sends a message through the confirm channel and waits for the broker ack
+func (q *Queue) publish(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	confirmation, err := q.publisher.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange, // exchange
+		key,      // routing key
+		false,    // mandatory
+		false,    // immediate
+		msg,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for publish confirmation: %w", err)
+	}
+	if !acked {
+		return errors.New("message was not acknowledged by the broker")
+	}
+
+	return nil
+}
+
+// publishMandatory sends a mandatory message through the retrier channel and
+// waits for the broker ack, failing when the broker returns it as unroutable
+func (q *Queue) publishMandatory(ctx context.Context, exchange, key string, msg amqp.Publishing) error {
+	q.retrierMu.Lock()
+	defer q.retrierMu.Unlock()
+
+	// Returns left by a publish that gave up waiting are not for this message
+	q.drainReturns()
+
+	confirmation, err := q.retrier.PublishWithDeferredConfirmWithContext(
+		ctx,
+		exchange, // exchange
+		key,      // routing key
+		true,     // mandatory
+		false,    // immediate
+		msg,
+	)
+	if err != nil {
+		return fmt.Errorf("failed to publish message: %w", err)
+	}
+
+	acked, err := confirmation.WaitContext(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to wait for publish confirmation: %w", err)
+	}
+	if !acked {
+		return errors.New("message was not acknowledged by the broker")
+	}
+
+	for _, ret := range q.drainReturns() {
+		if ret.MessageId == msg.MessageId {
+			return fmt.Errorf("message was returned by the broker: %s", ret.ReplyText)
+		}
+	}
+
+	return nil
+}
+
+// drainReturns takes every return buffered on the retrier channel
+func (q *Queue) drainReturns() []amqp.Return {
+	var returns []amqp.Return
+	for {
+		select {
+		case ret := <-q.returns:
+			returns = append(returns, ret)
+		default:
+			return returns
+		}
+	}
+}
+
 func (q *Queue) declareQueues() error {
-	queues := []string{VotesQueueName}
-
-	for _, queue := range queues {
-		_, err := q.channel.QueueDeclare(
-			queue, // name
-			true,  // durable
-			false, // delete when unused
-			false, // exclusive
-			false, // no-wait
-			nil,   // arguments
-		)
-		if err != nil {
-			return fmt.Errorf("failed to declare queue: %w", err)
+	for _, queue := range Queues {
+		if err := q.declareQueue(queue, nil); err != nil {
+			return err
+		}
+
+		// Dead-lettered messages are kept until they are requeued or purged by an admin
+		if err := q.declareQueue(DeadLetterQueueName(queue), nil); err != nil {
+			return err
+		}
+
+		// Each retry queue holds messages for its delay and then hands them
+		// back to the work queue through the default exchange
+		for i, delay := range RetryDelays {
+			name := RetryQueueName(queue, i+1)
+			args := amqp.Table{
+				"x-message-ttl":             delay.Milliseconds(),
+				"x-dead-letter-exchange":    "",
+				"x-dead-letter-routing-key": queue,
+			}
+			if err := q.declareQueue(name, args); err != nil {
+				return err
+			}
+			if err := q.queueBind(name, name, RetryExchangeName); err != nil {
+				return fmt.Errorf("failed to bind retry queue: %w", err)
+			}
 		}
 	}
 
 	return nil
 }
 
+func (q *Queue) declareQueue(name string, args amqp.Table) error {
+	_, err := q.channel.QueueDeclare(
+		name,  // name
+		true,  // durable
+		false, // delete when unused
+		false, // exclusive
+		false, // no-wait
+		args,  // arguments
+	)
+	if err != nil {
+		return fmt.Errorf("failed to declare queue %s: %w", name, err)
+	}
+
+	return nil
+}
+
 func (q *Queue) declareExchange() error {
-	return q.channel.ExchangeDeclare(
+	err := q.channel.ExchangeDeclare(
 		MainExchangeName, // name
 		"topic",          // type
 		true,             // durable
@@ -107,6 +443,19 @@ func (q *Queue) declareExchange() error {
 		false,            // no-wait
 		nil,              // arguments
 	)
+	if err != nil {
+		return err
+	}
+
+	return q.channel.ExchangeDeclare(
+		RetryExchangeName, // name
+		"direct",          // type
+		true,              // durable
+		false,             // auto-delete
+		false,             // internal
+		false,             // no-wait
+		nil,               // arguments
+	)
 }
 
 func (q *Queue) queueBind(queueName, routingKey, exchangeName string) error {
@@ -118,3 +467,54 @@ func (q *Queue) queueBind(queueName, routingKey, exchangeName string) error {
 		nil,          // arguments
 	)
 }
+
+// republish builds a persistent copy of a delivery with the given headers
+func republish(d amqp.Delivery, headers amqp.Table) amqp.Publishing {
+	return amqp.Publishing{
+		Headers:      headers,
+		ContentType:  d.ContentType,
+		DeliveryMode: amqp.Persistent,
+		MessageId:    d.MessageId,
+		Type:         d.Type,
+		Timestamp:    d.Timestamp,
+		Body:         d.Body,
+	}
+}
+
+func copyHeaders(headers amqp.Table) amqp.Table {
+	var cp = amqp.Table{}
+	for k, v := range headers {
+		cp[k] = v
+	}
+	return cp
+}
+
+func retryCount(headers amqp.Table) int {
+	switch v := headers[retryCountHeader].(type) {
+	case int32:
+		return int(v)
+	case int64:
+		return int(v)
+	case int:
+		return v
+	default:
+		return 0
+	}
+}
+
+func newDeadLetter(d amqp.Delivery) DeadLetter {
+	letter := DeadLetter{
+		MessageID:  d.MessageId,
+		RoutingKey: d.RoutingKey,
+		Retries:    retryCount(d.Headers),
+		Body:       string(d.Body),
+	}
+	if v, ok := d.Headers[errorHeader].(string); ok {
+		letter.Error = v
+	}
+	if v, ok := d.Headers[deadLetteredAtHeader].(time.Time); ok {
+		letter.DeadLetteredAt = v
+	}
+
+	return letter
+}
@@ -0,0 +1,41 @@
+package dto
+
+import "time"
+
+type CreateElimination struct {
+	// Type is one of elimination, immunity or finale, elimination by default
+	Type string `json:"type"`
+	// VoteMode is either eliminate or save, save eliminates the least voted
+	// participant and is only allowed in elimination polls
+	VoteMode string `json:"vote_mode"`
+	// EliminatedCount is how many participants are eliminated, 1 by default
+	EliminatedCount int        `json:"eliminated_count"`
+	Participants    []string   `json:"participants"`
+	StartDate       *time.Time `json:"start_date"`
+	EndDate         *time.Time `json:"end_date"`
+	// TieBreakPolicy is one of earliest_vote, priority or manual
+	TieBreakPolicy   string   `json:"tie_break_policy"`
+	TieBreakPriority []string `json:"tie_break_priority"`
+	// VotingScheme is either single or dual_pool, dual_pool requires the pool weights
+	VotingScheme   string `json:"voting_scheme"`
+	VerifiedWeight int    `json:"verified_weight"`
+	FanWeight      int    `json:"fan_weight"`
+}
+
+type DecideTie struct {
+	ParticipantID string `json:"participant_id"`
+}
+
+type RemoveParticipant struct {
+	ParticipantID string `json:"participant_id"`
+	Reason        string `json:"reason"`
+	// ActorID is the user removing the participant, taken from the token
+	ActorID string `json:"-"`
+}
+
+type DashboardQuery struct {
+	// Bucket is the width of each point of the time series, one of 1m, 5m or 1h
+	Bucket string
+	// Timezone is the IANA zone the buckets are aligned to
+	Timezone string
+}
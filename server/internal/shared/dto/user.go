@@ -11,6 +11,9 @@ type Register struct {
 type Login struct {
 	Email    string `json:"email"`
 	Password string `json:"password"`
+	// ClientID and ClientSecret identify the app the user logs in from
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
 }
 
 type LoginResponse struct {
@@ -20,9 +23,11 @@ type LoginResponse struct {
 }
 
 type UserResponse struct {
-	ID      string    `json:"id"`
-	Name    string    `json:"name"`
-	Email   string    `json:"email"`
-	Created time.Time `json:"created"`
-	Updated time.Time `json:"updated"`
+	ID       string    `json:"id"`
+	Name     string    `json:"name"`
+	Email    string    `json:"email"`
+	Verified bool      `json:"verified"`
+	Role     string    `json:"role"`
+	Created  time.Time `json:"created"`
+	Updated  time.Time `json:"updated"`
 }
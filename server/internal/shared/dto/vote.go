@@ -1,7 +1,20 @@
 package dto
 
 type CreateVote struct {
-	UserID        string `json:"user_id"`
+	// UserID comes from the token of the voter, never from the body
+	UserID string `json:"-"`
+	// EliminationID comes from the URL, never from the body
+	EliminationID string `json:"-"`
 	ParticipantID string `json:"participant_id"`
-	EliminationID string `json:"elimination_id"`
+	// Challenge and Solution are the solved proof-of-work challenge of the vote
+	Challenge string `json:"challenge"`
+	Solution  string `json:"solution"`
+	// Pool is either verified or fan, fan when empty
+	Pool string `json:"pool"`
+	// IP is the address the vote was sent from
+	IP string `json:"-"`
+	// Verified comes from the token of the voter, never from the body
+	Verified bool `json:"-"`
+	// Origin is the channel of the authenticated client, never read from the body
+	Origin string `json:"-"`
 }
@@ -36,6 +36,32 @@ func ExecTx(ctx context.Context, db *sqlx.DB, fn func(*sqlx.Tx) error) error {
 	return nil
 }
 
+// WithAdvisoryLock runs fn only if the PostgreSQL session advisory lock identified
+// by key could be acquired, so a single instance runs fn at a time
+// It reports whether the lock was acquired
+func WithAdvisoryLock(ctx context.Context, db *sqlx.DB, key int64, fn func() error) (bool, error) {
+	// Session locks belong to a connection, so the same one must unlock it
+	conn, err := db.Connx(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to get connection: %w", err)
+	}
+	defer conn.Close()
+
+	var acquired bool
+	err = conn.GetContext(ctx, &acquired, "SELECT pg_try_advisory_lock($1)", key)
+	if err != nil {
+		return false, fmt.Errorf("failed to acquire advisory lock: %w", err)
+	}
+	if !acquired {
+		return false, nil
+	}
+	defer func() {
+		_, _ = conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", key)
+	}()
+
+	return true, fn()
+}
+
 // ParseKey extracts the field name from a pg error detail string
 // The detail string should contain a pattern in the format "Key (field)=value"
 func ParseKey(detail string) string {
@@ -2,13 +2,20 @@ package errs
 
 import (
 	"encoding/json"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 func HttpError(w http.ResponseWriter, err error) {
 	w.Header().Set("Content-Type", "application/json")
 
 	if err, ok := err.(ApplicationError); ok {
+		if err.RetryAfter > 0 {
+			seconds := math.Ceil(err.RetryAfter.Seconds())
+			w.Header().Set("Retry-After", strconv.Itoa(int(seconds)))
+		}
 		w.WriteHeader(err.StatusCode())
 		_ = json.NewEncoder(w).Encode(err)
 		return
@@ -52,3 +59,10 @@ func NewConflictError(msg string, err error) ApplicationError {
 	httpCode := http.StatusConflict
 	return NewAppError(httpCode, ResourceConflict, msg, err)
 }
+
+func NewTooManyRequestsError(msg string, retryAfter time.Duration, err error) ApplicationError {
+	httpCode := http.StatusTooManyRequests
+	appErr := NewAppError(httpCode, RateLimited, msg, err)
+	appErr.RetryAfter = retryAfter
+	return appErr
+}
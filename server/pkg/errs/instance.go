@@ -2,20 +2,28 @@ package errs
 
 import (
 	"fmt"
+	"time"
 )
 
 type ErrorCode string
 
 const (
-	AccessTokenUnauthorized ErrorCode = "ACCESS_TOKEN_UNAUTHORIZED"
-	InternalServerError     ErrorCode = "INTERNAL_SERVER_ERROR"
-	BadRequest              ErrorCode = "BAD_REQUEST"
-	InvalidCredentials      ErrorCode = "INVALID_CREDENTIALS"
-	NotFound                ErrorCode = "NOT_FOUND"
-	Expired                 ErrorCode = "EXPIRED"
-	InvalidField            ErrorCode = "INVALID_FIELD"
-	ResourceConflict        ErrorCode = "RESOURCE_ALREADY_TAKEN"
-	ResourceLimitReached    ErrorCode = "RESOURCE_LIMIT_REACHED"
+	AccessTokenUnauthorized     ErrorCode = "ACCESS_TOKEN_UNAUTHORIZED"
+	InternalServerError         ErrorCode = "INTERNAL_SERVER_ERROR"
+	BadRequest                  ErrorCode = "BAD_REQUEST"
+	InvalidCredentials          ErrorCode = "INVALID_CREDENTIALS"
+	NotFound                    ErrorCode = "NOT_FOUND"
+	Expired                     ErrorCode = "EXPIRED"
+	InvalidField                ErrorCode = "INVALID_FIELD"
+	ResourceConflict            ErrorCode = "RESOURCE_ALREADY_TAKEN"
+	ResourceLimitReached        ErrorCode = "RESOURCE_LIMIT_REACHED"
+	InvalidState                ErrorCode = "INVALID_STATE"
+	InvalidChallenge            ErrorCode = "INVALID_CHALLENGE"
+	RateLimited                 ErrorCode = "RATE_LIMITED"
+	EliminationNotOpen          ErrorCode = "ELIMINATION_NOT_OPEN"
+	OutsideVotingPeriod         ErrorCode = "OUTSIDE_VOTING_PERIOD"
+	ParticipantNotInElimination ErrorCode = "PARTICIPANT_NOT_IN_ELIMINATION"
+	AdminRequired               ErrorCode = "ADMIN_REQUIRED"
 )
 
 type ApplicationError struct {
@@ -23,6 +31,8 @@ type ApplicationError struct {
 	Err      error     `json:"-"`
 	Code     ErrorCode `json:"code"`
 	Msg      string    `json:"message"`
+	// RetryAfter is sent as the Retry-After header when set
+	RetryAfter time.Duration `json:"-"`
 }
 
 func NewAppError(httpCode int, code ErrorCode, msg string, err error) ApplicationError {
@@ -0,0 +1,162 @@
+package merkle
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+)
+
+// Leaves and inner nodes are hashed with different prefixes, so an inner
+// node can never be passed off as a leaf
+const (
+	leafPrefix  = 0x00
+	innerPrefix = 0x01
+)
+
+// Side tells on which side of the running hash a proof step is combined
+type Side string
+
+const (
+	Left  Side = "left"
+	Right Side = "right"
+)
+
+// Step is a sibling hash on the path from a leaf to the root
+type Step struct {
+	Hash string `json:"hash"`
+	Side Side   `json:"side"`
+}
+
+// Node is the position of a node in a tree, level 0 holds the leaves and the
+// last level holds the root
+type Node struct {
+	Level    int
+	Position int
+}
+
+// Sibling is a node combined with the running hash on the path to the root
+type Sibling struct {
+	Node
+	Side Side
+}
+
+// Leaf returns the hash of a leaf value
+func Leaf(value string) []byte {
+	h := sha256.New()
+	h.Write([]byte{leafPrefix})
+	h.Write([]byte(value))
+	return h.Sum(nil)
+}
+
+func inner(left, right []byte) []byte {
+	h := sha256.New()
+	h.Write([]byte{innerPrefix})
+	h.Write(left)
+	h.Write(right)
+	return h.Sum(nil)
+}
+
+// next returns the level above the given one
+// A node without a sibling is carried to the next level unchanged
+func next(level [][]byte) [][]byte {
+	var parents = make([][]byte, 0, (len(level)+1)/2)
+	for i := 0; i < len(level); i += 2 {
+		if i+1 == len(level) {
+			parents = append(parents, level[i])
+			continue
+		}
+		parents = append(parents, inner(level[i], level[i+1]))
+	}
+	return parents
+}
+
+func leaves(values []string) [][]byte {
+	var level = make([][]byte, 0, len(values))
+	for _, v := range values {
+		level = append(level, Leaf(v))
+	}
+	return level
+}
+
+// Root returns the hex encoded root of the tree built over values, in the order given
+// The root of an empty tree is the hash of no data
+func Root(values []string) string {
+	if len(values) == 0 {
+		sum := sha256.Sum256(nil)
+		return hex.EncodeToString(sum[:])
+	}
+
+	level := leaves(values)
+	for len(level) > 1 {
+		level = next(level)
+	}
+	return hex.EncodeToString(level[0])
+}
+
+// Levels returns every level of the tree built over values, in the order
+// given, from the leaves to the root
+func Levels(values []string) [][][]byte {
+	if len(values) == 0 {
+		return nil
+	}
+
+	var levels = [][][]byte{leaves(values)}
+	for level := levels[0]; len(level) > 1; {
+		level = next(level)
+		levels = append(levels, level)
+	}
+	return levels
+}
+
+// Path returns the siblings on the path from the leaf at index to the root of
+// a tree with size leaves, so a proof can be served from stored levels
+func Path(size, index int) []Sibling {
+	var path []Sibling
+	for level := 0; size > 1; level++ {
+		sibling := index ^ 1
+		if sibling < size {
+			side := Right
+			if sibling < index {
+				side = Left
+			}
+			path = append(path, Sibling{Node: Node{Level: level, Position: sibling}, Side: side})
+		}
+		size = (size + 1) / 2
+		index /= 2
+	}
+	return path
+}
+
+// Proof returns the path proving that values[index] is in the tree
+func Proof(values []string, index int) []Step {
+	var steps []Step
+
+	levels := Levels(values)
+	for _, s := range Path(len(values), index) {
+		steps = append(steps, Step{Hash: hex.EncodeToString(levels[s.Level][s.Position]), Side: s.Side})
+	}
+
+	return steps
+}
+
+// Verify reports whether the proof leads from value to the hex encoded root
+func Verify(value string, proof []Step, root string) bool {
+	hash := Leaf(value)
+	for _, step := range proof {
+		sibling, err := hex.DecodeString(step.Hash)
+		if err != nil {
+			return false
+		}
+		switch step.Side {
+		case Left:
+			hash = inner(sibling, hash)
+		case Right:
+			hash = inner(hash, sibling)
+		default:
+			return false
+		}
+	}
+
+	expected, err := hex.DecodeString(root)
+	return err == nil && bytes.Equal(hash, expected)
+}
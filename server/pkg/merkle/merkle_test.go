@@ -0,0 +1,91 @@
+package merkle
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+func values(n int) []string {
+	var values = make([]string, 0, n)
+	for i := range n {
+		values = append(values, fmt.Sprintf("vote-%d", i))
+	}
+	return values
+}
+
+func TestProof(t *testing.T) {
+	for _, size := range []int{1, 2, 3, 4, 5, 7, 8, 13} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			values := values(size)
+			root := Root(values)
+
+			for i, v := range values {
+				if !Verify(v, Proof(values, i), root) {
+					t.Errorf("Verify() of leaf %d = false, want true", i)
+				}
+			}
+		})
+	}
+}
+
+func TestProofSingleLeaf(t *testing.T) {
+	values := []string{"vote-0"}
+
+	if got, want := Root(values), hex.EncodeToString(Leaf("vote-0")); got != want {
+		t.Errorf("Root() = %s, want %s", got, want)
+	}
+	if proof := Proof(values, 0); len(proof) != 0 {
+		t.Errorf("Proof() = %v, want no steps", proof)
+	}
+	if !Verify("vote-0", nil, Root(values)) {
+		t.Error("Verify() = false, want true")
+	}
+}
+
+func TestProofRejects(t *testing.T) {
+	values := values(5)
+	root := Root(values)
+	proof := Proof(values, 2)
+
+	tests := []struct {
+		name  string
+		value string
+		proof []Step
+		root  string
+	}{
+		{name: "foreign leaf", value: "vote-99", proof: proof, root: root},
+		{name: "another leaf's proof", value: values[3], proof: proof, root: root},
+		{name: "other tree", value: values[2], proof: proof, root: Root(values[:4])},
+		{name: "missing step", value: values[2], proof: proof[1:], root: root},
+		{name: "swapped side", value: values[2], proof: append([]Step{{Hash: proof[0].Hash, Side: Left}}, proof[1:]...), root: root},
+		{name: "unknown side", value: values[2], proof: append([]Step{{Hash: proof[0].Hash, Side: "up"}}, proof[1:]...), root: root},
+		{name: "malformed hash", value: values[2], proof: append([]Step{{Hash: "zz", Side: proof[0].Side}}, proof[1:]...), root: root},
+		{name: "malformed root", value: values[2], proof: proof, root: "zz"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if Verify(tt.value, tt.proof, tt.root) {
+				t.Error("Verify() = true, want false")
+			}
+		})
+	}
+}
+
+func TestLevels(t *testing.T) {
+	for _, size := range []int{1, 3, 5, 7} {
+		t.Run(fmt.Sprintf("%d leaves", size), func(t *testing.T) {
+			values := values(size)
+			levels := Levels(values)
+
+			top := levels[len(levels)-1]
+			if len(top) != 1 {
+				t.Fatalf("last level has %d nodes, want 1", len(top))
+			}
+			if got, want := hex.EncodeToString(top[0]), Root(values); got != want {
+				t.Errorf("last level = %s, want root %s", got, want)
+			}
+		})
+	}
+}
@@ -16,11 +16,12 @@ const chartConfig = {
 export function BarChartComponent(props: {
 	shouldAnimate?: boolean
 	data: {
-		hour: number
+		hour: number | string
 		votes: number
 	}[]
+	formatTick?: (value: number | string) => string
 }) {
-	const { shouldAnimate = true, data } = props
+	const { shouldAnimate = true, data, formatTick = (value) => `${value}h` } = props
 
 	return (
 		<ChartContainer config={chartConfig} className="aspect-auto h-[250px] w-full">
@@ -39,7 +40,7 @@ export function BarChartComponent(props: {
 					tickLine={false}
 					tickMargin={12}
 					axisLine={false}
-					tickFormatter={(value) => `${value}h`}
+					tickFormatter={formatTick}
 				/>
 				<ChartTooltip
 					content={<ChartTooltipContent nameKey="votes" className="w-[90px]" />}
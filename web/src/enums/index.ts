@@ -7,6 +7,9 @@ export enum ErrCodes {
   ResourceNotFound = "RESOURCE_NOT_FOUND",
   ResourceAlreadyTaken = "RESOURCE_ALREADY_TAKEN",
   LimitReached = "RESOURCE_LIMIT_REACHED",
+  InvalidState = "INVALID_STATE",
   CaptchaNotVerified = "CAPTCHA_NOT_VERIFIED",
+  InvalidChallenge = "INVALID_CHALLENGE",
+  RateLimited = "RATE_LIMITED",
   Unauthorized = "ACCESS_TOKEN_UNAUTHORIZED",
 }
@@ -4,7 +4,7 @@ import type { DialogProps } from "@/src/components/dialog/root"
 import { Field } from "@/src/components/field"
 import { Select } from "@/src/components/select"
 import { ErrCodes } from "@/src/enums"
-import type { Participant } from "@/src/types"
+import type { Participant, PollType, VoteMode } from "@/src/types"
 import { getQueryClient } from "@/src/util/get-query-client"
 import { isHTTPError } from "@/src/util/http/http-error"
 import { request } from "@/src/util/http/request"
@@ -16,8 +16,21 @@ import { z } from "zod"
 
 const formId = "create-elimination-form"
 
+const pollTypes: { label: string; value: PollType }[] = [
+	{ label: "Paredão", value: "elimination" },
+	{ label: "Imunidade", value: "immunity" },
+	{ label: "Final", value: "finale" },
+]
+
+const voteModes: { label: string; value: VoteMode }[] = [
+	{ label: "Votar para eliminar", value: "eliminate" },
+	{ label: "Votar para salvar", value: "save" },
+]
+
 const schema = z
 	.object({
+		type: z.enum(["elimination", "immunity", "finale"]),
+		voteMode: z.enum(["eliminate", "save"]),
 		participantA: z.string().min(1, "Selecione um participante"),
 		participantB: z.string().min(1, "Selecione um participante"),
 	})
@@ -44,6 +57,8 @@ export function CreateEliminationDialog(
 	const form = useForm<z.infer<typeof schema>>({
 		resolver: zodResolver(schema),
 		defaultValues: {
+			type: "elimination",
+			voteMode: "eliminate",
 			participantA: "",
 			participantB: "",
 		},
@@ -54,13 +69,15 @@ export function CreateEliminationDialog(
 		props.onOpenChange(open)
 	}
 
-	// TODO: block submission if there is already an ongoing elimination
 	const onSubmit: SubmitHandler<z.infer<typeof schema>> = async (data) => {
 		try {
 			await request({
 				path: "api/v1/eliminations",
 				method: "POST",
 				data: {
+					type: data.type,
+					// Only elimination polls can ask who should stay
+					vote_mode: data.type === "elimination" ? data.voteMode : "eliminate",
 					participants: [data.participantA, data.participantB],
 				},
 			})
@@ -75,9 +92,8 @@ export function CreateEliminationDialog(
 			onChangeDialog(false)
 		} catch (err) {
 			if (isHTTPError(err)) {
-				if (err.code === ErrCodes.LimitReached) {
-					onChangeDialog(false)
-					toast.error("Você atingiu o limite de paredões ativos")
+				if (err.code === ErrCodes.InvalidState) {
+					toast.error("Participantes eliminados ou desistentes não podem entrar em uma votação")
 					return
 				}
 				toast.error("Algo inesperado aconteceu, tente novamente mais tarde")
@@ -102,6 +118,36 @@ export function CreateEliminationDialog(
 							className="space-y-4"
 							onSubmit={form.handleSubmit(onSubmit)}
 						>
+							<Controller
+								control={form.control}
+								name="type"
+								render={({ field, fieldState }) => (
+									<Field label="Tipo de votação" message={fieldState.error?.message}>
+										<Select
+											value={field.value}
+											onValueChange={field.onChange}
+											items={pollTypes}
+										/>
+									</Field>
+								)}
+							/>
+
+							{form.watch("type") === "elimination" && (
+								<Controller
+									control={form.control}
+									name="voteMode"
+									render={({ field, fieldState }) => (
+										<Field label="Modo de votação" message={fieldState.error?.message}>
+											<Select
+												value={field.value}
+												onValueChange={field.onChange}
+												items={voteModes}
+											/>
+										</Field>
+									)}
+								/>
+							)}
+
 							<Controller
 								control={form.control}
 								name="participantA"
@@ -115,7 +161,9 @@ export function CreateEliminationDialog(
 												participants?.map((participant) => ({
 													label: participant.name,
 													value: participant.id,
-													disabled: !!participant.elimination_id,
+													disabled:
+														participant.status === "eliminated" ||
+														participant.status === "withdrawn",
 												})) ?? []
 											}
 										/>
@@ -136,7 +184,9 @@ export function CreateEliminationDialog(
 												participants?.map((participant) => ({
 													label: participant.name,
 													value: participant.id,
-													disabled: !!participant.elimination_id,
+													disabled:
+														participant.status === "eliminated" ||
+														participant.status === "withdrawn",
 												})) ?? []
 											}
 										/>
@@ -153,7 +203,7 @@ export function CreateEliminationDialog(
 					</Dialog.Notice>
 				) : (
 					<Dialog.Notice intent="warning">
-						Participantes desabilitados já estão participando de outro paredão
+						Participantes desabilitados foram eliminados ou deixaram a casa
 					</Dialog.Notice>
 				)}
 			</Dialog.Content>
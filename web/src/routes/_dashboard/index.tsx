@@ -5,38 +5,74 @@ import { BarChartComponent } from "@/src/components/charts/bar-chart"
 import { EmptyState } from "@/src/components/empty-state"
 import { PageLayout } from "@/src/components/layout/page-layout"
 import { DashboardSkeleton } from "@/src/modules/dashboard/components/dashboard-skeleton"
+import type { Elimination } from "@/src/types"
 import { request } from "@/src/util/http/request"
 import { useQuery } from "@tanstack/react-query"
 import { createFileRoute } from "@tanstack/react-router"
 import { ChartArea, ChartAreaIcon, TrendingUp, Users2 } from "lucide-react"
 import { AnimatePresence, motion } from "motion/react"
 
+type SeriesPoint = {
+	start: string
+	votes: number
+}
+
 type DashboardResult = {
+	elimination_id: string
 	total_votes: number
 	total_users: number
 	votes_per_hour: number
-	spread_votes: number[]
+	bucket: string
+	timezone: string
+	series: SeriesPoint[]
+	peak: SeriesPoint | null
 	has_elimination: boolean
 }
 
+const timezone = Intl.DateTimeFormat().resolvedOptions().timeZone
+
+function formatBucket(start: string) {
+	return new Date(start).toLocaleString("pt-BR", {
+		timeZone: timezone,
+		day: "2-digit",
+		month: "2-digit",
+		hour: "2-digit",
+		minute: "2-digit",
+	})
+}
+
 function RouteComponent() {
+	const { data: eliminations, isFetching: isFetchingEliminations } = useQuery({
+		queryKey: ["eliminations-open"],
+		queryFn: () => {
+			return request<Elimination[]>({
+				path: "api/v1/eliminations/open",
+				method: "GET",
+			})
+		},
+	})
+
+	// Several polls can run at once, the dashboard follows the newest one
+	const eliminationId = eliminations?.[0]?.id
+
 	const {
 		data,
 		isFetching: isFetchingDashboard,
 		isRefetching: isRefetchingDashboard,
 		refetch: refetchDashboard,
 	} = useQuery({
-		queryKey: ["dashboard"],
+		queryKey: ["dashboard", eliminationId],
 		queryFn: () => {
 			return request<DashboardResult>({
-				path: "api/v1/eliminations/dashboard",
+				path: `api/v1/eliminations/${eliminationId}/dashboard?bucket=1h&tz=${encodeURIComponent(timezone)}`,
 				method: "GET",
 			})
 		},
+		enabled: !!eliminationId,
 		refetchInterval: 1000 * 30, // 30 seconds
 	})
 
-	const hasActiveElimination = data?.has_elimination
+	const hasActiveElimination = !!eliminationId && data?.has_elimination
 
 	return (
 		<>
@@ -68,7 +104,7 @@ function RouteComponent() {
 					</AnimatePresence>
 				}
 			>
-				{isFetchingDashboard && !isRefetchingDashboard ? (
+				{isFetchingEliminations || (isFetchingDashboard && !isRefetchingDashboard) ? (
 					<DashboardSkeleton />
 				) : !hasActiveElimination ? (
 					<EmptyState
@@ -87,7 +123,7 @@ function RouteComponent() {
 											<div className="flex flex-col">
 												<Card.Title>Total de votos</Card.Title>
 												<Card.Description>
-													Total de votos registrados no sistema
+													Total de votos registrados na eliminação
 												</Card.Description>
 											</div>
 
@@ -110,7 +146,7 @@ function RouteComponent() {
 											<div className="flex flex-col">
 												<Card.Title>Total de usuários</Card.Title>
 												<Card.Description>
-													Total de usuários registrados no sistema
+													Usuários que votaram na eliminação
 												</Card.Description>
 											</div>
 
@@ -133,7 +169,7 @@ function RouteComponent() {
 											<div className="flex flex-col">
 												<Card.Title>Média de votos</Card.Title>
 												<Card.Description>
-													Média de votos registrados no sistema por hora
+													Votos por hora desde a abertura da eliminação
 												</Card.Description>
 											</div>
 
@@ -153,7 +189,8 @@ function RouteComponent() {
 							<Card.Header>
 								<Card.Title>Votos por hora</Card.Title>
 								<Card.Description>
-									Total de votos registrados no sistema por hora
+									Votos registrados em cada hora da eliminação
+									{data?.peak && `, com pico em ${formatBucket(data.peak.start)}`}
 								</Card.Description>
 							</Card.Header>
 
@@ -161,10 +198,11 @@ function RouteComponent() {
 								<Card.Row>
 									<BarChartComponent
 										data={
-											data?.spread_votes.map((el, index) => {
-												return { hour: index, votes: el }
+											data?.series.map((point) => {
+												return { hour: formatBucket(point.start), votes: point.votes }
 											}) ?? []
 										}
+										formatTick={(value) => String(value)}
 									/>
 								</Card.Row>
 							</Card.Body>
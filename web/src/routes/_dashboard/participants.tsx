@@ -130,7 +130,7 @@ function RouteComponent() {
                       {format(participant.created, "PPP", { locale: ptBR })}
                     </Table.Cell>
                     <Table.Cell>
-                      {participant.elimination_id ? (
+                      {participant.status === "in_elimination" ? (
                         <Badge intent="danger">Em paredão</Badge>
                       ) : (
                         <Badge intent="success">Disponível</Badge>
@@ -143,7 +143,7 @@ function RouteComponent() {
                           variant="secondary"
                           onClick={() => {
                             // TODO: Add this validation to the backend
-                            if (participant.elimination_id) {
+                            if (participant.status === "in_elimination") {
                               toast.error("Este participante está em um paredão")
                               return
                             }
@@ -5,11 +5,12 @@ import { PageLayout } from "@/src/components/layout/page-layout"
 import { ErrCodes } from "@/src/enums"
 import { VotingSkeleton } from "@/src/modules/eliminations/voting-skeleton"
 import { ConfirmVote } from "@/src/modules/voting/components/confirm-vote"
-import type { Elimination } from "@/src/types"
+import type { Elimination, PollType } from "@/src/types"
 import { cn } from "@/src/util/cn"
 import { getQueryClient } from "@/src/util/get-query-client"
 import { isHTTPError } from "@/src/util/http/http-error"
 import { request } from "@/src/util/http/request"
+import { type Challenge, solveChallenge } from "@/src/util/solve-challenge"
 import { useMutation, useQuery } from "@tanstack/react-query"
 import { createFileRoute, useNavigate } from "@tanstack/react-router"
 import { formatDistanceToNow } from "date-fns"
@@ -19,6 +20,13 @@ import { Users2 } from "lucide-react"
 import { parseAsBoolean, useQueryState } from "nuqs"
 import { toast } from "sonner"
 
+const titles: Record<PollType | "save", string> = {
+	elimination: "Qual participante você quer eliminar?",
+	save: "Qual participante você quer salvar?",
+	immunity: "Qual participante deve ganhar a imunidade?",
+	finale: "Qual participante deve vencer o BBB 25?",
+}
+
 function RouteComponent() {
 	const query = getQueryClient()
 	const navigate = useNavigate({ from: "/voting" })
@@ -40,12 +48,20 @@ function RouteComponent() {
 		isPending: isVoting,
 		variables: participantId,
 	} = useMutation({
-		mutationFn: (participantId: string) => {
+		mutationFn: async (participantId: string) => {
+			const challenge = await request<Challenge>({
+				path: `api/v1/eliminations/${elimination?.id}/challenge`,
+				method: "GET",
+			})
+			const solution = await solveChallenge(challenge)
+
 			return request({
 				path: `api/v1/eliminations/${elimination?.id}/vote`,
 				method: "POST",
 				data: {
 					participant_id: participantId,
+					challenge: challenge.challenge,
+					solution,
 				},
 			})
 		},
@@ -58,6 +74,14 @@ function RouteComponent() {
 		},
 		onError: (err) => {
 			if (isHTTPError(err)) {
+				if (err.code === ErrCodes.RateLimited) {
+					toast.error("Você está votando rápido demais, aguarde alguns segundos")
+					return
+				}
+				if (err.code === ErrCodes.InvalidChallenge) {
+					toast.error("Não foi possível validar o seu voto, tente novamente")
+					return
+				}
 				if (err.code === ErrCodes.CaptchaNotVerified) {
 					toast.error("Captcha não verificado")
 					return
@@ -79,8 +103,10 @@ function RouteComponent() {
 		<ConfirmVote eliminationId={elimination?.id as string} />
 	) : (
 		<PageLayout
-			title="Qual participante você quer eliminar?"
-			description="Selecione um participante para eliminar do BBB 25"
+			title={
+				titles[elimination?.vote_mode === "save" ? "save" : (elimination?.type ?? "elimination")]
+			}
+			description="Selecione um participante para votar"
 		>
 			{isLoadingEliminations ? (
 				<VotingSkeleton />
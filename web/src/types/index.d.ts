@@ -10,14 +10,21 @@ export type Participant = {
   id: string
   name: string
   picture: string | null
-  elimination_id: string | null
+  status: "active" | "in_elimination" | "eliminated" | "withdrawn"
   created: Date
   updated: Date
 }
 
+export type PollType = "elimination" | "immunity" | "finale"
+
+export type VoteMode = "eliminate" | "save"
+
 export type Elimination = {
   id: string
   open: boolean
+  type: PollType
+  vote_mode: VoteMode
+  eliminated_count: number
   participants: Pick<Participant, "id" | "name">[]
   start_date: Date
   end_date: Date
@@ -0,0 +1,34 @@
+export type Challenge = {
+  challenge: string
+  difficulty: number
+  expires: string
+}
+
+/**
+ * Finds a solution for a vote challenge, a string for which
+ * `sha256(challenge + ":" + solution)` starts with `difficulty` zero bits
+ *
+ * @returns {Promise<string>} The solution to send along with the vote
+ */
+export async function solveChallenge({ challenge, difficulty }: Challenge): Promise<string> {
+  const encoder = new TextEncoder()
+
+  for (let nonce = 0; ; nonce++) {
+    const solution = nonce.toString(36)
+    const hash = await crypto.subtle.digest("SHA-256", encoder.encode(`${challenge}:${solution}`))
+    if (leadingZeros(new Uint8Array(hash)) >= difficulty) {
+      return solution
+    }
+  }
+}
+
+function leadingZeros(hash: Uint8Array) {
+  let count = 0
+  for (const byte of hash) {
+    if (byte !== 0) {
+      return count + Math.clz32(byte) - 24
+    }
+    count += 8
+  }
+  return count
+}